@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"e2b.dev/mcp-gateway/pkg/auth"
+	"e2b.dev/mcp-gateway/pkg/containers"
 	"e2b.dev/mcp-gateway/pkg/gateway"
 	"e2b.dev/mcp-gateway/pkg/health"
 	"github.com/urfave/cli/v2"
@@ -91,10 +92,38 @@ func runDaemon(ctx context.Context, c *cli.Context, host string, port int) error
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 
+	// Without Docker only remote servers can run, loading a config with container servers fails then.
+	// A runtime that was asked for explicitly has to work.
+	runtime, err := containers.New(c.String("runtime"))
+	if err == nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err = runtime.Ping(pingCtx); err != nil {
+			runtime.Close()
+		}
+		cancel()
+	}
+	switch {
+	case err != nil && c.IsSet("runtime"):
+		return fmt.Errorf("container runtime %q unavailable: %w", c.String("runtime"), err)
+	case err != nil:
+		zap.L().Warn("container runtime unavailable, only remote servers can be configured", zap.Error(err))
+		runtime = nil
+	default:
+		defer runtime.Close()
+
+		// Remove containers left behind by a gateway that crashed
+		if removed, err := gateway.CleanupContainers(ctx, runtime, false); err != nil {
+			zap.L().Warn("failed to clean up orphaned containers", zap.Error(err))
+		} else if removed > 0 {
+			zap.L().Info("orphaned containers removed", zap.Int("count", removed))
+		}
+	}
+
 	g, err := gateway.New(
 		ctx,
 		catalogs,
 		mapping,
+		runtime,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to initialize gateway: %w", err)
@@ -110,7 +139,7 @@ func runDaemon(ctx context.Context, c *cli.Context, host string, port int) error
 	}
 
 	// Setup and run HTTP server
-	srv := gateway.SetupHTTPServer(g.Server(), addr, token)
+	srv := gateway.SetupHTTPServer(g, addr, token)
 
 	zap.L().Info("server starting", zap.String("addr", addr))
 	gateway.Run(srv, g.Pool())
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"e2b.dev/mcp-gateway/pkg/containers"
 	"e2b.dev/mcp-gateway/pkg/gateway"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
@@ -20,21 +21,130 @@ func main() {
 		Usage: "MCP Gateway Server",
 		Commands: []*cli.Command{
 			{
-				Name:  "pull",
-				Usage: "Pull Docker images for specified services (by beautified name)",
+				Name:      "pull",
+				Usage:     "Pull Docker images for specified services (by beautified name)",
+				ArgsUsage: "[SERVICE...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "pull the images of every catalog server",
+					},
+					&cli.StringSliceFlag{
+						Name:  "category",
+						Usage: "pull the images of catalog servers in this category (can be specified multiple times)",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Value: 4,
+						Usage: "number of images pulled at the same time",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "write progress to stdout as one JSON event per line",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					ctx := context.Background()
 
-					// Ensure Docker config path like in run
-					os.Setenv("DOCKER_CONFIG", "/root/.docker")
+					// Registry credentials are read from the same Docker config as in run
+					os.Setenv("DOCKER_CONFIG", c.String("docker-config"))
+
+					selection := gateway.ServerSelection{
+						Names:      c.Args().Slice(),
+						Categories: c.StringSlice("category"),
+						All:        c.Bool("all"),
+					}
+					if len(selection.Names) == 0 && len(selection.Categories) == 0 && !selection.All {
+						return fmt.Errorf("please specify at least one service to pull, --category or --all")
+					}
 
-					args := c.Args().Slice()
-					if len(args) == 0 {
-						return fmt.Errorf("please specify at least one service to pull")
+					opts := gateway.PullOptions{Parallelism: c.Int("parallel")}
+					if c.Bool("json") {
+						opts.Progress = os.Stdout
 					}
 
+					runtime, err := containers.New(c.String("runtime"))
+					if err != nil {
+						return err
+					}
+					defer runtime.Close()
+
 					// Delegate to gateway logic
-					return gateway.PullImages(ctx, c.StringSlice("catalog"), c.String("mapping"), args)
+					return gateway.PullImages(ctx, runtime, c.StringSlice("catalog"), c.String("mapping"), []byte(c.String("config")), selection, opts)
+				},
+			},
+			{
+				Name:  "bundle",
+				Usage: "Move MCP server images into environments without registry access",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "export",
+						Usage:     "Write the images of the specified services (by beautified name) into a tarball",
+						ArgsUsage: "SERVICE...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "output",
+								Aliases:  []string{"o"},
+								Required: true,
+								Usage:    "bundle file to write",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							os.Setenv("DOCKER_CONFIG", c.String("docker-config"))
+
+							args := c.Args().Slice()
+							if len(args) == 0 {
+								return fmt.Errorf("please specify at least one service to export")
+							}
+
+							runtime, err := containers.New(c.String("runtime"))
+							if err != nil {
+								return err
+							}
+							defer runtime.Close()
+
+							return gateway.ExportBundle(context.Background(), runtime, c.StringSlice("catalog"), c.String("mapping"), []byte(c.String("config")), args, c.String("output"))
+						},
+					},
+					{
+						Name:      "import",
+						Usage:     "Load the images of a bundle and verify them against the catalog",
+						ArgsUsage: "BUNDLE",
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return fmt.Errorf("please specify the bundle to import")
+							}
+
+							runtime, err := containers.New(c.String("runtime"))
+							if err != nil {
+								return err
+							}
+							defer runtime.Close()
+
+							return gateway.ImportBundle(context.Background(), runtime, c.StringSlice("catalog"), c.Args().First())
+						},
+					},
+				},
+			},
+			{
+				Name:  "cleanup",
+				Usage: "Remove MCP server containers left behind by gateways that are no longer running",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "also remove containers of running gateways",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					runtime, err := containers.New(c.String("runtime"))
+					if err != nil {
+						return err
+					}
+					defer runtime.Close()
+
+					removed, err := gateway.CleanupContainers(context.Background(), runtime, c.Bool("all"))
+					zap.L().Info("cleanup finished", zap.Int("removed", removed))
+					return err
 				},
 			},
 		},
@@ -67,6 +177,18 @@ func main() {
 				Name:  "config",
 				Usage: "configuration JSON",
 			},
+			&cli.StringFlag{
+				Name:    "runtime",
+				Value:   containers.DockerName,
+				EnvVars: []string{"MCP_GATEWAY_RUNTIME"},
+				Usage:   "container runtime running the servers: docker or podman",
+			},
+			&cli.StringFlag{
+				Name:    "docker-config",
+				Value:   "/root/.docker",
+				EnvVars: []string{"DOCKER_CONFIG"},
+				Usage:   "Docker config directory holding registry credentials",
+			},
 			&cli.StringFlag{
 				Name:  "token",
 				Usage: "authentication token (enables auth middleware)",
@@ -99,7 +221,7 @@ func main() {
 func run(c *cli.Context) error {
 	ctx := context.Background()
 
-	os.Setenv("DOCKER_CONFIG", "/root/.docker")
+	os.Setenv("DOCKER_CONFIG", c.String("docker-config"))
 
 	host := c.String("host")
 	port := c.Int("port")
@@ -0,0 +1,288 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/utils"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// dockerRuntime runs containers through the Docker Engine API
+type dockerRuntime struct {
+	name   string
+	client *client.Client
+}
+
+// NewDocker returns the Docker runtime configured from the environment (DOCKER_HOST etc.)
+func NewDocker() (Runtime, error) {
+	engine, err := utils.NewEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return NewDockerWithClient(engine), nil
+}
+
+// NewDockerWithClient returns the Docker runtime using an existing Engine API client, Close closes it
+func NewDockerWithClient(engine *client.Client) Runtime {
+	return &dockerRuntime{name: DockerName, client: engine}
+}
+
+// Name implements Runtime
+func (d *dockerRuntime) Name() string {
+	return d.name
+}
+
+// EngineClient returns the Engine API client of the Docker runtime, false for other runtimes
+func EngineClient(r Runtime) (*client.Client, bool) {
+	d, ok := r.(*dockerRuntime)
+	if !ok {
+		return nil, false
+	}
+	return d.client, true
+}
+
+// Ping implements Runtime
+func (d *dockerRuntime) Ping(ctx context.Context) error {
+	if _, err := d.client.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to reach %s: %w", d.name, err)
+	}
+	return nil
+}
+
+// Local implements Runtime
+func (d *dockerRuntime) Local() bool {
+	host := d.client.DaemonHost()
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
+
+// ImagePresent implements Runtime
+func (d *dockerRuntime) ImagePresent(ctx context.Context, ref string) (bool, error) {
+	if _, err := d.client.ImageInspect(ctx, ref); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+	return true, nil
+}
+
+// Pull implements Runtime
+func (d *dockerRuntime) Pull(ctx context.Context, ref string, registryAuth string) error {
+	progress, err := d.client.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer progress.Close()
+
+	// Errors during the pull are reported in the progress stream
+	if err := jsonmessage.DisplayJSONMessagesStream(progress, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// Run implements Runtime
+func (d *dockerRuntime) Run(ctx context.Context, spec Spec) (*Attached, error) {
+	created, err := d.client.ContainerCreate(ctx, spec.Config, spec.HostConfig, nil, nil, spec.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	id := created.ID
+
+	// Attach and subscribe to the exit before starting so no output or exit is missed
+	hijacked, err := d.client.ContainerAttach(ctx, id, container.AttachOptions{Stream: true, Stdin: true, Stdout: true, Stderr: true})
+	if err != nil {
+		d.Remove(context.WithoutCancel(ctx), id)
+		return nil, fmt.Errorf("failed to attach to container: %w", err)
+	}
+	waitCh, waitErrCh := d.client.ContainerWait(context.WithoutCancel(ctx), id, container.WaitConditionNextExit)
+
+	if err := d.client.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		hijacked.Close()
+		d.Remove(context.WithoutCancel(ctx), id)
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Without a TTY stdout and stderr are multiplexed on the attach stream
+	stdout, stdoutWriter := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(stdoutWriter, io.Discard, hijacked.Reader)
+		stdoutWriter.CloseWithError(err)
+	}()
+
+	exit := make(chan ExitStatus, 1)
+	go func() {
+		var status ExitStatus
+		select {
+		case result := <-waitCh:
+			status.Code = result.StatusCode
+			if result.Error != nil {
+				status.Err = fmt.Errorf("%s", result.Error.Message)
+			}
+		case err := <-waitErrCh:
+			status.Err = err
+		}
+		if info, err := d.Inspect(context.Background(), id); err == nil {
+			status.OOMKilled = info.OOMKilled
+		}
+		exit <- status
+	}()
+
+	return &Attached{
+		ID:     id,
+		Stdin:  attachedStdin{hijacked},
+		Stdout: stdout,
+		Exit:   exit,
+		detach: hijacked.Close,
+	}, nil
+}
+
+// attachedStdin writes to the stdin of an attached container
+type attachedStdin struct {
+	hijacked types.HijackedResponse
+}
+
+// Write implements io.Writer
+func (s attachedStdin) Write(p []byte) (int, error) {
+	return s.hijacked.Conn.Write(p)
+}
+
+// Close closes the write side of the attach connection only
+func (s attachedStdin) Close() error {
+	return s.hijacked.CloseWrite()
+}
+
+// Stop implements Runtime
+func (d *dockerRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	seconds := int(timeout.Seconds())
+	if err := d.client.ContainerStop(ctx, id, container.StopOptions{Timeout: &seconds}); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Remove implements Runtime
+func (d *dockerRuntime) Remove(ctx context.Context, id string) error {
+	if err := d.client.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// List implements Runtime
+func (d *dockerRuntime) List(ctx context.Context, labels map[string]string) ([]Info, error) {
+	summaries, err := d.client.ContainerList(ctx, container.ListOptions{All: true, Filters: labelFilters(labels)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	infos := make([]Info, 0, len(summaries))
+	for _, summary := range summaries {
+		info := Info{
+			ID:     summary.ID,
+			Image:  summary.Image,
+			Labels: summary.Labels,
+			State:  string(summary.State),
+		}
+		if len(summary.Names) > 0 {
+			info.Name = strings.TrimPrefix(summary.Names[0], "/")
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Inspect implements Runtime
+func (d *dockerRuntime) Inspect(ctx context.Context, id string) (Info, error) {
+	inspect, err := d.client.ContainerInspect(ctx, id)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return Info{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return Info{}, err
+	}
+
+	info := Info{
+		ID:   inspect.ID,
+		Name: strings.TrimPrefix(inspect.Name, "/"),
+	}
+	if inspect.Config != nil {
+		info.Image = inspect.Config.Image
+		info.Labels = inspect.Config.Labels
+	}
+	if inspect.State != nil {
+		info.State = string(inspect.State.Status)
+		info.ExitCode = int64(inspect.State.ExitCode)
+		info.OOMKilled = inspect.State.OOMKilled
+	}
+	return info, nil
+}
+
+// EnsureNetwork implements Runtime
+func (d *dockerRuntime) EnsureNetwork(ctx context.Context, name string, labels map[string]string) (Network, error) {
+	inspect, err := d.client.NetworkInspect(ctx, name, network.InspectOptions{})
+	if errdefs.IsNotFound(err) {
+		_, err = d.client.NetworkCreate(ctx, name, network.CreateOptions{
+			Driver:   "bridge",
+			Internal: true,
+			Labels:   labels,
+		})
+		if err != nil && !errdefs.IsConflict(err) {
+			return Network{}, fmt.Errorf("failed to create network %s: %w", name, err)
+		}
+		inspect, err = d.client.NetworkInspect(ctx, name, network.InspectOptions{})
+	}
+	if err != nil {
+		return Network{}, fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+	if !inspect.Internal {
+		return Network{}, fmt.Errorf("network %s exists but is not internal", name)
+	}
+
+	for _, c := range inspect.IPAM.Config {
+		if ip := net.ParseIP(c.Gateway); ip != nil && ip.To4() != nil {
+			return Network{Name: name, Gateway: c.Gateway, Subnet: c.Subnet}, nil
+		}
+	}
+	return Network{}, fmt.Errorf("network %s has no IPv4 gateway", name)
+}
+
+// PruneNetworks implements Runtime
+func (d *dockerRuntime) PruneNetworks(ctx context.Context, labels map[string]string) ([]string, error) {
+	report, err := d.client.NetworksPrune(ctx, labelFilters(labels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune networks: %w", err)
+	}
+	return report.NetworksDeleted, nil
+}
+
+// labelFilters returns the filters matching all labels, an empty value matches any value of the label
+func labelFilters(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	for key, value := range labels {
+		if value == "" {
+			args.Add("label", key)
+		} else {
+			args.Add("label", key+"="+value)
+		}
+	}
+	return args
+}
+
+// Close implements Runtime
+func (d *dockerRuntime) Close() error {
+	return d.client.Close()
+}
@@ -0,0 +1,268 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FakeName is the name of the in-process runtime
+const FakeName = "fake"
+
+// FakeServer is the process of a fake container. It serves stdin and stdout until it returns
+// its exit code or ctx is cancelled because the container is stopped.
+type FakeServer func(ctx context.Context, stdin io.Reader, stdout io.Writer) int
+
+// Fake is an in-process Runtime that runs Go functions in place of images,
+// so the transport and the session pool can be exercised without a container daemon
+type Fake struct {
+	mu         sync.Mutex
+	servers    map[string]FakeServer // Pullable images
+	local      map[string]bool       // Images present locally
+	containers map[string]*fakeContainer
+	networks   map[string]map[string]string // Labels keyed by network name
+	pulls      []string
+	nextID     int
+}
+
+// fakeContainer is a container of the fake runtime
+type fakeContainer struct {
+	info    Info
+	network string
+	cancel  func()
+	exited  chan struct{}
+}
+
+// NewFake returns an empty fake runtime
+func NewFake() *Fake {
+	return &Fake{
+		servers:    make(map[string]FakeServer),
+		local:      make(map[string]bool),
+		containers: make(map[string]*fakeContainer),
+		networks:   make(map[string]map[string]string),
+	}
+}
+
+// AddImage makes an image pullable, running server in its containers. With present set the
+// image is also stored locally.
+func (f *Fake) AddImage(ref string, server FakeServer, present bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.servers[ref] = server
+	if present {
+		f.local[ref] = true
+	}
+}
+
+// Pulls returns the images pulled so far in pull order
+func (f *Fake) Pulls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.pulls...)
+}
+
+// Name implements Runtime
+func (f *Fake) Name() string {
+	return FakeName
+}
+
+// Ping implements Runtime
+func (f *Fake) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Local implements Runtime
+func (f *Fake) Local() bool {
+	return true
+}
+
+// ImagePresent implements Runtime
+func (f *Fake) ImagePresent(ctx context.Context, ref string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.local[ref], nil
+}
+
+// Pull implements Runtime
+func (f *Fake) Pull(ctx context.Context, ref string, registryAuth string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.servers[ref]; !ok {
+		return fmt.Errorf("failed to pull image %s: repository does not exist", ref)
+	}
+	f.local[ref] = true
+	f.pulls = append(f.pulls, ref)
+	return nil
+}
+
+// Run implements Runtime
+func (f *Fake) Run(ctx context.Context, spec Spec) (*Attached, error) {
+	f.mu.Lock()
+	server, ok := f.servers[spec.Config.Image]
+	if !ok || !f.local[spec.Config.Image] {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("failed to create container: no such image: %s", spec.Config.Image)
+	}
+	f.nextID++
+	id := fmt.Sprintf("fake%012d", f.nextID)
+	stdin, stdinWriter := io.Pipe()
+	stdout, stdoutWriter := io.Pipe()
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	cancel := func() {
+		// Unblock a server waiting for input like a killed process would be
+		cancelRun()
+		stdin.CloseWithError(io.ErrClosedPipe)
+	}
+	c := &fakeContainer{
+		info: Info{
+			ID:     id,
+			Name:   spec.Name,
+			Image:  spec.Config.Image,
+			Labels: maps.Clone(spec.Config.Labels),
+			State:  "running",
+		},
+		cancel: cancel,
+		exited: make(chan struct{}),
+	}
+	if spec.HostConfig != nil {
+		c.network = string(spec.HostConfig.NetworkMode)
+	}
+	f.containers[id] = c
+	f.mu.Unlock()
+
+	exit := make(chan ExitStatus, 1)
+	go func() {
+		code := server(runCtx, stdin, stdoutWriter)
+		stdoutWriter.Close()
+		stdin.Close()
+
+		f.mu.Lock()
+		c.info.State = "exited"
+		c.info.ExitCode = int64(code)
+		f.mu.Unlock()
+		close(c.exited)
+		exit <- ExitStatus{Code: int64(code)}
+	}()
+
+	return &Attached{
+		ID:     id,
+		Stdin:  stdinWriter,
+		Stdout: stdout,
+		Exit:   exit,
+		detach: func() { stdoutWriter.Close() },
+	}, nil
+}
+
+// Stop implements Runtime
+func (f *Fake) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	f.mu.Lock()
+	c, ok := f.containers[id]
+	f.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	c.cancel()
+	select {
+	case <-c.exited:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("container %s did not stop", id)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Remove implements Runtime
+func (f *Fake) Remove(ctx context.Context, id string) error {
+	f.mu.Lock()
+	c, ok := f.containers[id]
+	delete(f.containers, id)
+	f.mu.Unlock()
+	if ok {
+		c.cancel()
+	}
+	return nil
+}
+
+// List implements Runtime
+func (f *Fake) List(ctx context.Context, labels map[string]string) ([]Info, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var infos []Info
+	for _, c := range f.containers {
+		if matchLabels(c.info.Labels, labels) {
+			infos = append(infos, c.info)
+		}
+	}
+	return infos, nil
+}
+
+// matchLabels reports whether a container has all labels, an empty value matches any value
+func matchLabels(have, want map[string]string) bool {
+	for key, value := range want {
+		got, ok := have[key]
+		if !ok || (value != "" && got != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Inspect implements Runtime
+func (f *Fake) Inspect(ctx context.Context, id string) (Info, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.containers[id]
+	if !ok {
+		return Info{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return c.info, nil
+}
+
+// EnsureNetwork implements Runtime. Fake containers run in-process, so every network is the loopback network of the host.
+func (f *Fake) EnsureNetwork(ctx context.Context, name string, labels map[string]string) (Network, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.networks[name]; !ok {
+		f.networks[name] = maps.Clone(labels)
+	}
+	return Network{Name: name, Gateway: "127.0.0.1", Subnet: "127.0.0.0/8"}, nil
+}
+
+// PruneNetworks implements Runtime
+func (f *Fake) PruneNetworks(ctx context.Context, labels map[string]string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	used := make(map[string]bool)
+	for _, c := range f.containers {
+		used[c.network] = true
+	}
+	var removed []string
+	for name, networkLabels := range f.networks {
+		if !used[name] && matchLabels(networkLabels, labels) {
+			delete(f.networks, name)
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(removed)
+	return removed, nil
+}
+
+// Networks returns the names of the networks that exist, sorted
+func (f *Fake) Networks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Sorted(maps.Keys(f.networks))
+}
+
+// Close implements Runtime
+func (f *Fake) Close() error {
+	return nil
+}
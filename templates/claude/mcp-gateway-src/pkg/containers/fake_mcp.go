@@ -0,0 +1,77 @@
+package containers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// FakeMCPServer returns the process of a fake container serving an MCP server over its stdio,
+// like the servers of the catalog do. It exits once its stdin closes.
+func FakeMCPServer(server *mcp.Server) FakeServer {
+	return func(ctx context.Context, stdin io.Reader, stdout io.Writer) int {
+		session, err := server.Connect(ctx, &fakeStdioTransport{stdin: stdin, stdout: stdout}, nil)
+		if err != nil {
+			return 1
+		}
+		session.Wait()
+		return 0
+	}
+}
+
+// fakeStdioTransport speaks newline-delimited JSON-RPC over the stdio of a fake container
+type fakeStdioTransport struct {
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// Connect implements mcp.Transport
+func (t *fakeStdioTransport) Connect(context.Context) (mcp.Connection, error) {
+	scanner := bufio.NewScanner(t.stdin)
+	scanner.Buffer(nil, 64<<20)
+	return &fakeStdioConn{scanner: scanner, stdout: t.stdout}, nil
+}
+
+// fakeStdioConn is the connection of fakeStdioTransport
+type fakeStdioConn struct {
+	scanner *bufio.Scanner
+	writeMu sync.Mutex
+	stdout  io.Writer
+}
+
+// Read implements mcp.Connection
+func (c *fakeStdioConn) Read(context.Context) (jsonrpc.Message, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return jsonrpc.DecodeMessage(c.scanner.Bytes())
+}
+
+// Write implements mcp.Connection
+func (c *fakeStdioConn) Write(_ context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdout.Write(append(data, '\n'))
+	return err
+}
+
+// Close implements mcp.Connection
+func (c *fakeStdioConn) Close() error {
+	return nil
+}
+
+// SessionID implements mcp.Connection
+func (c *fakeStdioConn) SessionID() string {
+	return ""
+}
@@ -0,0 +1,77 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"e2b.dev/mcp-gateway/pkg/utils"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/client"
+)
+
+// podmanRootSocket is the socket of the rootful Podman service
+const podmanRootSocket = "/run/podman/podman.sock"
+
+// podmanRuntime runs containers through the Docker-compatible API of the Podman service.
+// Podman resolves short image names through its registries configuration, so references
+// are fully qualified before they are sent.
+type podmanRuntime struct {
+	*dockerRuntime
+}
+
+// NewPodman returns the Podman runtime. The service is found through CONTAINER_HOST, then
+// the rootless socket under XDG_RUNTIME_DIR, then the rootful socket.
+func NewPodman() (Runtime, error) {
+	engine, err := client.NewClientWithOpts(
+		client.WithHost(podmanHost()),
+		client.WithAPIVersionNegotiation(),
+		client.WithUserAgent(utils.UserAgent),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create podman client: %w", err)
+	}
+	return &podmanRuntime{dockerRuntime: &dockerRuntime{name: PodmanName, client: engine}}, nil
+}
+
+// podmanHost returns the address of the Podman service
+func podmanHost() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		socket := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket
+		}
+	}
+	return "unix://" + podmanRootSocket
+}
+
+// qualifyImage expands a reference to its fully qualified form, e.g. mcp/x to docker.io/mcp/x
+func qualifyImage(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return named.String()
+}
+
+// ImagePresent implements Runtime
+func (p *podmanRuntime) ImagePresent(ctx context.Context, ref string) (bool, error) {
+	return p.dockerRuntime.ImagePresent(ctx, qualifyImage(ref))
+}
+
+// Pull implements Runtime
+func (p *podmanRuntime) Pull(ctx context.Context, ref string, registryAuth string) error {
+	return p.dockerRuntime.Pull(ctx, qualifyImage(ref), registryAuth)
+}
+
+// Run implements Runtime
+func (p *podmanRuntime) Run(ctx context.Context, spec Spec) (*Attached, error) {
+	config := *spec.Config
+	config.Image = qualifyImage(config.Image)
+	spec.Config = &config
+	return p.dockerRuntime.Run(ctx, spec)
+}
@@ -0,0 +1,131 @@
+// Package containers runs MCP server containers on a container runtime.
+//
+// The gateway only needs a small set of operations from a runtime: pulling images, running a
+// container attached to its stdio, stopping, listing by label and inspecting containers, and
+// creating and pruning the internal networks that restrict the egress of servers.
+// Docker and Podman implement them through the Engine API, Fake runs servers in-process.
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Runtime names
+const (
+	DockerName = "docker"
+	PodmanName = "podman"
+)
+
+// ErrNotFound is returned when a container does not exist
+var ErrNotFound = errors.New("container not found")
+
+// Runtime is a container runtime the Docker transport runs servers on
+type Runtime interface {
+	// Name identifies the runtime in logs
+	Name() string
+
+	// Local reports whether containers run on this host, so that host paths can be bind-mounted
+	Local() bool
+
+	// Ping checks that the runtime is reachable
+	Ping(ctx context.Context) error
+
+	// ImagePresent reports whether an image is stored locally
+	ImagePresent(ctx context.Context, ref string) (bool, error)
+
+	// Pull pulls an image, registryAuth is the encoded X-Registry-Auth value or empty
+	Pull(ctx context.Context, ref string, registryAuth string) error
+
+	// Run creates and starts a container attached to its stdin and stdout
+	Run(ctx context.Context, spec Spec) (*Attached, error)
+
+	// Stop stops a container, killing it once timeout elapsed. Stopped or missing containers are no error.
+	Stop(ctx context.Context, id string, timeout time.Duration) error
+
+	// Remove deletes a container, stopping it first. Missing containers are no error.
+	Remove(ctx context.Context, id string) error
+
+	// List returns every container carrying all labels, an empty value matches any value of the label
+	List(ctx context.Context, labels map[string]string) ([]Info, error)
+
+	// Inspect returns the state of a container, ErrNotFound when it does not exist
+	Inspect(ctx context.Context, id string) (Info, error)
+
+	// EnsureNetwork returns the internal network with the given name, creating it when missing.
+	// Containers on an internal network only reach each other and the host's address on it.
+	EnsureNetwork(ctx context.Context, name string, labels map[string]string) (Network, error)
+
+	// PruneNetworks removes the networks carrying all labels that no container is attached to
+	// and returns their names
+	PruneNetworks(ctx context.Context, labels map[string]string) ([]string, error)
+
+	// Close releases the connection to the runtime
+	Close() error
+}
+
+// Spec describes a container to run.
+// It uses the Engine API types, which Podman's Docker-compatible API understands as well.
+type Spec struct {
+	Name       string
+	Config     *container.Config
+	HostConfig *container.HostConfig
+}
+
+// Info describes a container
+type Info struct {
+	ID        string
+	Name      string
+	Image     string
+	Labels    map[string]string
+	State     string // "created", "running", "exited", ...
+	ExitCode  int64
+	OOMKilled bool
+}
+
+// Network is an internal network containers can be attached to
+type Network struct {
+	Name    string
+	Gateway string // IPv4 address of the host on the network
+	Subnet  string // IPv4 subnet of the network in CIDR notation
+}
+
+// ExitStatus is how a container stopped
+type ExitStatus struct {
+	Code      int64
+	OOMKilled bool
+	Err       error // Waiting for the container failed
+}
+
+// Attached is a running container whose stdio is attached
+type Attached struct {
+	ID     string
+	Stdin  io.WriteCloser    // Closing it closes the container's stdin, stdout stays readable
+	Stdout io.Reader         // Stdout of the container, stderr is discarded
+	Exit   <-chan ExitStatus // Receives the exit status once the container stopped
+	detach func()
+}
+
+// Detach releases the attached streams
+func (a *Attached) Detach() {
+	if a.detach != nil {
+		a.detach()
+	}
+}
+
+// New returns the runtime with the given name, Docker when name is empty
+func New(name string) (Runtime, error) {
+	switch name {
+	case "", DockerName:
+		return NewDocker()
+	case PodmanName:
+		return NewPodman()
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q", name)
+	}
+}
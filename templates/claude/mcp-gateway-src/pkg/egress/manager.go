@@ -0,0 +1,131 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"go.uber.org/zap"
+)
+
+// DefaultNetwork prefixes the internal networks servers with an egress policy are attached to
+const DefaultNetwork = "mcp-gateway-egress"
+
+// Config controls where the filtering proxies run
+type Config struct {
+	Network    string // Prefix of the internal networks, one per server, created when missing
+	ListenHost string // Address the proxies listen on, defaults to the gateway IP of the server's network
+}
+
+// Manager runs one filtering proxy and one internal network per server. Containers on an internal
+// network can't reach the internet or other networks directly, only the proxy of their server
+// listening on the network's gateway address on the host. Each proxy also refuses clients from
+// outside its server's network.
+type Manager struct {
+	mu      sync.Mutex
+	cfg     Config
+	runtime containers.Runtime
+	proxies map[string]*runningProxy
+}
+
+// runningProxy is a proxy listening for a single server
+type runningProxy struct {
+	proxy   *Proxy
+	server  *http.Server
+	network string
+	gateway string // Address of the host on the network
+	url     string
+}
+
+// NewManager creates a manager creating networks on runtime, networks and proxies are set up on first use
+func NewManager(cfg Config, runtime containers.Runtime) *Manager {
+	if cfg.Network == "" {
+		cfg.Network = DefaultNetwork
+	}
+	return &Manager{cfg: cfg, runtime: runtime, proxies: make(map[string]*runningProxy)}
+}
+
+// Endpoint returns the network a server's container joins and the URL of the proxy it must use
+func (m *Manager) Endpoint(ctx context.Context, serverName string, allowHosts []string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.runtime == nil {
+		return "", "", fmt.Errorf("no container runtime to create the egress network of %s", serverName)
+	}
+
+	// Unused networks are pruned by cleanup, possibly of another gateway, so the network is ensured on every call
+	network, err := m.runtime.EnsureNetwork(ctx, m.cfg.Network+"-"+serverName, map[string]string{
+		"docker-mcp":      "true",
+		"docker-mcp-name": serverName,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	policy := NewPolicy(allowHosts)
+	if running, ok := m.proxies[serverName]; ok {
+		if running.network == network.Name && running.gateway == network.Gateway {
+			// Catalog changes take effect without restarting the listener
+			running.proxy.SetPolicy(policy)
+			return running.network, running.url, nil
+		}
+
+		// The network was recreated with another address, the proxy has to listen on the new one
+		running.server.Close()
+		delete(m.proxies, serverName)
+	}
+	_, clients, err := net.ParseCIDR(network.Subnet)
+	if err != nil {
+		return "", "", fmt.Errorf("network %s has an invalid subnet %q: %w", network.Name, network.Subnet, err)
+	}
+
+	listenHost := m.cfg.ListenHost
+	if listenHost == "" {
+		listenHost = network.Gateway
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenHost, "0"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to listen for egress proxy: %w", err)
+	}
+
+	proxy := &Proxy{Server: serverName, Policy: policy, Clients: clients}
+	server := &http.Server{Handler: proxy, ReadHeaderTimeout: 30 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("Egress proxy stopped", zap.String("component", "EGRESS"), zap.String("server", serverName), zap.Error(err))
+		}
+	}()
+
+	running := &runningProxy{proxy: proxy, server: server, network: network.Name, gateway: network.Gateway, url: "http://" + listener.Addr().String()}
+	m.proxies[serverName] = running
+
+	zap.L().Info("Egress proxy started",
+		zap.String("component", "EGRESS"),
+		zap.String("server", serverName),
+		zap.String("network", network.Name),
+		zap.String("addr", listener.Addr().String()),
+		zap.Strings("allowHosts", allowHosts))
+
+	return running.network, running.url, nil
+}
+
+// Close stops all proxies
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for name, running := range m.proxies {
+		if err := running.server.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(m.proxies, name)
+	}
+	return firstErr
+}
@@ -0,0 +1,64 @@
+package egress
+
+import (
+	"context"
+	"testing"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+)
+
+func TestManagerGivesEachServerItsOwnNetworkAndProxy(t *testing.T) {
+	manager := NewManager(Config{}, containers.NewFake())
+	defer manager.Close()
+
+	ctx := context.Background()
+	braveNetwork, braveURL, err := manager.Endpoint(ctx, "brave", []string{"api.search.brave.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	githubNetwork, githubURL, err := manager.Endpoint(ctx, "github", []string{"api.github.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if braveNetwork != DefaultNetwork+"-brave" || githubNetwork != DefaultNetwork+"-github" {
+		t.Errorf("networks: got %q and %q", braveNetwork, githubNetwork)
+	}
+	if braveURL == githubURL {
+		t.Errorf("servers share the proxy %s", braveURL)
+	}
+
+	// A policy change reuses the running proxy
+	network, url, err := manager.Endpoint(ctx, "brave", []string{"*.brave.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network != braveNetwork || url != braveURL {
+		t.Errorf("restarted proxy: got %s %s", network, url)
+	}
+	if !manager.proxies["brave"].proxy.allowed("api.search.brave.com", "443") {
+		t.Error("updated policy was not applied")
+	}
+}
+
+func TestManagerRecreatesPrunedNetworks(t *testing.T) {
+	runtime := containers.NewFake()
+	manager := NewManager(Config{}, runtime)
+	defer manager.Close()
+
+	ctx := context.Background()
+	if _, _, err := manager.Endpoint(ctx, "brave", []string{"api.search.brave.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runtime.PruneNetworks(ctx, map[string]string{"docker-mcp": "true"}); err != nil {
+		t.Fatal(err)
+	}
+
+	network, _, err := manager.Endpoint(ctx, "brave", []string{"api.search.brave.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networks := runtime.Networks(); len(networks) != 1 || networks[0] != network {
+		t.Errorf("got networks %v, want %s created again", networks, network)
+	}
+}
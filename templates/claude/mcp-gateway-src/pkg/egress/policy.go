@@ -0,0 +1,56 @@
+package egress
+
+import (
+	"net"
+	"strings"
+)
+
+// rule is a single allowed destination
+type rule struct {
+	host     string // Lower-case host name or IP, without a leading "*."
+	wildcard bool   // Also matches subdomains of host
+	port     string // Empty for any port
+}
+
+// Policy decides which destinations a server may connect to.
+// Entries follow the catalog's allowHosts format: "host", "host:port" or "*.domain[:port]".
+type Policy struct {
+	rules []rule
+}
+
+// NewPolicy builds a policy from allowHosts entries
+func NewPolicy(allowHosts []string) Policy {
+	var p Policy
+	for _, entry := range allowHosts {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		var r rule
+		if host, port, err := net.SplitHostPort(entry); err == nil {
+			r.host, r.port = host, port
+		} else {
+			r.host = entry
+		}
+		if rest, ok := strings.CutPrefix(r.host, "*."); ok {
+			r.host, r.wildcard = rest, true
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+// Allows reports whether a connection to host:port is permitted
+func (p Policy) Allows(host string, port string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, r := range p.rules {
+		if r.port != "" && r.port != port {
+			continue
+		}
+		if host == r.host || (r.wildcard && strings.HasSuffix(host, "."+r.host)) {
+			return true
+		}
+	}
+	return false
+}
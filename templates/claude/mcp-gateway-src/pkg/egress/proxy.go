@@ -0,0 +1,214 @@
+package egress
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// hopHeaders are connection-specific headers a proxy must not forward
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Proxy is a filtering HTTP proxy that tunnels CONNECT requests and forwards plain HTTP requests
+// to the destinations allowed by its policy
+type Proxy struct {
+	Server string // Server the proxy serves, used in logs
+	Policy Policy // Initial policy, replace it with SetPolicy once the proxy serves requests
+
+	// Clients is the network of the server's containers, connections from other addresses are
+	// refused so that containers of other servers can't use this server's policy. Nil serves any client.
+	Clients *net.IPNet
+
+	// Dial opens upstream connections, defaults to a net.Dialer. Tests can point it at a local upstream.
+	Dial func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+	mu            sync.RWMutex
+	transportOnce sync.Once
+	transport     *http.Transport
+}
+
+// SetPolicy replaces the policy of a running proxy
+func (p *Proxy) SetPolicy(policy Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Policy = policy
+}
+
+// ServeHTTP handles a proxied request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.clientAllowed(r.RemoteAddr) {
+		zap.L().Warn("Refused egress proxy client from outside the server's network",
+			zap.String("component", "EGRESS"),
+			zap.String("server", p.Server),
+			zap.String("client", r.RemoteAddr))
+		http.Error(w, "client not allowed to use this egress proxy", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+
+	if r.URL.Host == "" {
+		http.Error(w, "only proxy requests are accepted", http.StatusBadRequest)
+		return
+	}
+	if !p.allowed(r.URL.Hostname(), portOf(r.URL.Port(), r.URL.Scheme)) {
+		p.deny(w, r.URL.Host)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	removeHopHeaders(out.Header)
+
+	resp, err := p.upstream().RoundTrip(out)
+	if err != nil {
+		http.Error(w, "upstream request failed", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// handleConnect opens a tunnel to an allowed destination
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, "CONNECT needs host:port", http.StatusBadRequest)
+		return
+	}
+	if !p.allowed(host, port) {
+		p.deny(w, r.Host)
+		return
+	}
+
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, "upstream connection failed", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	// Bytes the client sent right after the CONNECT request are already buffered
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		upstream.Write(data)
+	}
+
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// clientAllowed reports whether a connection comes from the server's network
+func (p *Proxy) clientAllowed(remoteAddr string) bool {
+	if p.Clients == nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && p.Clients.Contains(ip)
+}
+
+// allowed checks a destination against the policy
+func (p *Proxy) allowed(host string, port string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.Policy.Allows(host, port)
+}
+
+// deny rejects a request and logs the destination
+func (p *Proxy) deny(w http.ResponseWriter, destination string) {
+	zap.L().Warn("Denied egress connection",
+		zap.String("component", "EGRESS"),
+		zap.String("server", p.Server),
+		zap.String("destination", destination))
+	http.Error(w, "destination not allowed by the gateway egress policy", http.StatusForbidden)
+}
+
+// dial opens an upstream connection
+func (p *Proxy) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if p.Dial != nil {
+		return p.Dial(ctx, network, addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// upstream returns the transport used to forward plain HTTP requests
+func (p *Proxy) upstream() *http.Transport {
+	p.transportOnce.Do(func() {
+		p.transport = &http.Transport{
+			DialContext:           p.dial,
+			MaxIdleConns:          16,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		}
+	})
+	return p.transport
+}
+
+// removeHopHeaders strips connection-specific headers, including the ones listed in Connection
+func removeHopHeaders(h http.Header) {
+	for _, field := range strings.Split(h.Get("Connection"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			h.Del(field)
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// portOf returns the explicit port or the default port of the scheme
+func portOf(port string, scheme string) string {
+	if port != "" {
+		return port
+	}
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
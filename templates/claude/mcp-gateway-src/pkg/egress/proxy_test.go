@@ -0,0 +1,111 @@
+package egress
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestProxy serves a proxy for allowed.test whose upstream connections all go to upstream
+func newTestProxy(t *testing.T, upstream *httptest.Server, clients *net.IPNet) *url.URL {
+	t.Helper()
+
+	upstreamAddr := upstream.Listener.Addr().String()
+	proxy := &Proxy{
+		Server:  "test",
+		Policy:  NewPolicy([]string{"allowed.test", "*.wild.test:443"}),
+		Clients: clients,
+		Dial: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, upstreamAddr)
+		},
+	}
+	server := httptest.NewServer(proxy)
+	t.Cleanup(server.Close)
+
+	proxyURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxyURL
+}
+
+// get fetches target through the proxy and returns the status code and body
+func get(t *testing.T, proxyURL *url.URL, target string) (int, string) {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(target)
+	if err != nil {
+		// CONNECT refusals surface as errors carrying the proxy's status
+		if strings.Contains(err.Error(), "Forbidden") {
+			return http.StatusForbidden, ""
+		}
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProxyForwardsAllowedHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello from "+r.Host)
+	}))
+	defer upstream.Close()
+	proxyURL := newTestProxy(t, upstream, nil)
+
+	status, body := get(t, proxyURL, "http://allowed.test/")
+	if status != http.StatusOK || body != "hello from allowed.test" {
+		t.Errorf("allowed host: got %d %q", status, body)
+	}
+
+	if status, _ := get(t, proxyURL, "http://denied.test/"); status != http.StatusForbidden {
+		t.Errorf("denied host: got %d, want 403", status)
+	}
+}
+
+func TestProxyTunnelsAllowedCONNECT(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "tunneled")
+	}))
+	defer upstream.Close()
+	proxyURL := newTestProxy(t, upstream, nil)
+
+	status, body := get(t, proxyURL, "https://api.wild.test/")
+	if status != http.StatusOK || body != "tunneled" {
+		t.Errorf("allowed tunnel: got %d %q", status, body)
+	}
+
+	if status, _ := get(t, proxyURL, "https://wild.test.evil.test/"); status != http.StatusForbidden {
+		t.Errorf("denied tunnel: got %d, want 403", status)
+	}
+}
+
+func TestProxyRefusesClientsOutsideNetwork(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "reached")
+	}))
+	defer upstream.Close()
+
+	_, other, _ := net.ParseCIDR("10.89.0.0/24")
+	if status, _ := get(t, newTestProxy(t, upstream, other), "http://allowed.test/"); status != http.StatusForbidden {
+		t.Errorf("client outside the network: got %d, want 403", status)
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	if status, body := get(t, newTestProxy(t, upstream, loopback), "http://allowed.test/"); status != http.StatusOK || body != "reached" {
+		t.Errorf("client on the network: got %d %q", status, body)
+	}
+}
@@ -0,0 +1,491 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// Names of the tools that manage servers at runtime
+const (
+	listServersToolName      = "gateway-list-servers"
+	serverConfigToolName     = "gateway-server-config"
+	activateServerToolName   = "gateway-activate-server"
+	deactivateServerToolName = "gateway-deactivate-server"
+)
+
+// defaultServerListLimit is the number of servers listed when the caller sets no limit
+const defaultServerListLimit = 25
+
+// serverSummary describes a catalog server for the listing tool
+type serverSummary struct {
+	Name        string `json:"name"`
+	ConfigKey   string `json:"configKey,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	Active      bool   `json:"active"`
+}
+
+// serverConfigKey describes a config value a server accepts
+type serverConfigKey struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"` // "secret" or "config"
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// activationTools returns the definitions of the server management tools
+func activationTools() []*mcp.Tool {
+	serverArg := map[string]any{"type": "string", "description": "Catalog server name or config key"}
+
+	return []*mcp.Tool{
+		{
+			Name:        listServersToolName,
+			Description: "List the MCP servers of the catalog that can be activated, optionally filtered by keywords.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":  map[string]any{"type": "string", "description": "Keywords matched against server names and descriptions"},
+					"active": map[string]any{"type": "boolean", "description": "Only list servers that are (true) or are not (false) active"},
+					"limit": map[string]any{
+						"type":        "integer",
+						"minimum":     1,
+						"description": fmt.Sprintf("Maximum number of servers to return (default %d)", defaultServerListLimit),
+					},
+				},
+			},
+		},
+		{
+			Name:        serverConfigToolName,
+			Description: "Show the config keys and secrets a catalog server needs before it can be activated.",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"server": serverArg},
+				"required":   []string{"server"},
+			},
+		},
+		{
+			Name:        activateServerToolName,
+			Description: "Start a catalog server with the given config and make its tools available.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"server": serverArg,
+					"config": map[string]any{"type": "object", "description": "Config values keyed as reported by " + serverConfigToolName},
+				},
+				"required": []string{"server"},
+			},
+		},
+		{
+			Name:        deactivateServerToolName,
+			Description: "Stop an active server and remove its tools.",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"server": serverArg},
+				"required":   []string{"server"},
+			},
+		},
+	}
+}
+
+// activationHandlers maps the server management tools to their handlers
+func (g *Gateway) activationHandlers() map[string]mcp.ToolHandler {
+	return map[string]mcp.ToolHandler{
+		listServersToolName:      g.handleListServers,
+		serverConfigToolName:     g.handleServerConfig,
+		activateServerToolName:   g.handleActivateServer,
+		deactivateServerToolName: g.handleDeactivateServer,
+	}
+}
+
+// resolveCatalogServer maps a catalog server name or config key to the catalog name and config key in servers
+func (g *Gateway) resolveCatalogServer(servers map[string]catalog.Server, name string) (string, string, bool) {
+	if serverName, ok := GetServerNameFromInstructions(g.instructionMap, name); ok {
+		if _, exists := servers[serverName]; exists {
+			return serverName, name, true
+		}
+	}
+	if _, exists := servers[name]; exists {
+		configKey, ok := configKeyForServer(g.instructionMap, name)
+		return name, configKey, ok
+	}
+	return "", "", false
+}
+
+// configKeyForServer finds the config key the instruction map uses for a catalog server
+func configKeyForServer(instructionMap InstructionMap, serverName string) (string, bool) {
+	var candidates []string
+	for key, instruction := range instructionMap {
+		if instruction.Server != serverName {
+			continue
+		}
+		if base, _, found := strings.Cut(key, "."); found {
+			candidates = append(candidates, base)
+		} else {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	return slices.Min(candidates), true
+}
+
+// serverConfigKeys lists the config values a catalog server accepts according to the instruction map
+func (g *Gateway) serverConfigKeys(serverName string, configKey string, server catalog.Server) []serverConfigKey {
+	required := make(map[string]bool)
+	descriptions := make(map[string]string)
+	for _, c := range server.Config {
+		schema, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if names, ok := schema["required"].([]any); ok {
+			for _, n := range names {
+				if s, ok := n.(string); ok {
+					required[s] = true
+				}
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]any); ok {
+			for name, property := range properties {
+				if p, ok := property.(map[string]any); ok {
+					if d, ok := p["description"].(string); ok {
+						descriptions[name] = d
+					}
+				}
+			}
+		}
+	}
+
+	secretEnvs := make(map[string]bool, len(server.Secrets))
+	for _, s := range server.Secrets {
+		secretEnvs[s.Env] = true
+	}
+
+	var keys []serverConfigKey
+	prefix := configKey + "."
+	for key, instruction := range g.instructionMap {
+		if instruction.Server != serverName || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		entry := serverConfigKey{Key: strings.TrimPrefix(key, prefix), Type: string(instruction.Type)}
+		switch instruction.Type {
+		case SecretInstruction:
+			entry.Required = secretEnvs[instruction.EnvName]
+		case ConfigInstruction:
+			if len(instruction.Path) > 0 {
+				entry.Required = required[instruction.Path[0]]
+				entry.Description = descriptions[instruction.Path[0]]
+			}
+		}
+		keys = append(keys, entry)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
+}
+
+// handleListServers lists and searches the catalog servers
+func (g *Gateway) handleListServers(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Query  string `json:"query"`
+		Active *bool  `json:"active"`
+		Limit  int    `json:"limit"`
+	}
+	if len(req.Params.Arguments) > 0 {
+		if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+			return gatewayError(errCodeInvalidArgs, fmt.Sprintf("Invalid arguments for %s: %v", listServersToolName, err), nil), nil
+		}
+	}
+	limit := defaultServerListLimit
+	if args.Limit > 0 {
+		limit = args.Limit
+	}
+
+	// Discovery holds toolsLoading while it pulls images, listing works on a snapshot instead
+	catalogServers, userConfigs := g.catalogState()
+
+	queryTerms := tokenize(args.Query)
+	type scored struct {
+		summary serverSummary
+		score   int
+	}
+	var matches []scored
+	for name, server := range catalogServers {
+		configKey, _ := configKeyForServer(g.instructionMap, name)
+		_, active := userConfigs[configKey]
+		if configKey == "" {
+			active = false
+		}
+		if args.Active != nil && *args.Active != active {
+			continue
+		}
+
+		score := 0
+		if len(queryTerms) > 0 {
+			terms := make(map[string]bool)
+			for _, t := range tokenize(strings.Join([]string{name, configKey, server.Title, server.Description}, " ")) {
+				terms[t] = true
+			}
+			for _, t := range queryTerms {
+				if terms[t] {
+					score++
+				}
+			}
+			if score == 0 {
+				continue
+			}
+		}
+
+		matches = append(matches, scored{
+			summary: serverSummary{
+				Name:        name,
+				ConfigKey:   configKey,
+				Title:       server.Title,
+				Description: server.Description,
+				Type:        server.Type,
+				Active:      active,
+			},
+			score: score,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].summary.Name < matches[j].summary.Name
+	})
+
+	servers := make([]serverSummary, 0, min(len(matches), limit))
+	for _, m := range matches[:min(len(matches), limit)] {
+		servers = append(servers, m.summary)
+	}
+
+	return structuredResult(map[string]any{"servers": servers, "total": len(matches)})
+}
+
+// handleServerConfig reports the config keys a catalog server needs
+func (g *Gateway) handleServerConfig(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Server string `json:"server"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Server == "" {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs a server name", serverConfigToolName), nil), nil
+	}
+
+	servers, userConfigs := g.catalogState()
+	serverName, configKey, ok := g.resolveCatalogServer(servers, args.Server)
+	if !ok {
+		return unknownServerError(args.Server), nil
+	}
+
+	_, active := userConfigs[configKey]
+	return structuredResult(map[string]any{
+		"server":    serverName,
+		"configKey": configKey,
+		"active":    active,
+		"keys":      g.serverConfigKeys(serverName, configKey, servers[serverName]),
+	})
+}
+
+// handleActivateServer merges the supplied config into a pristine copy of the catalog server, starts it
+// and registers its tools. The server is started without holding toolsLoading, so other calls aren't blocked
+// while its image is pulled, and an active server is only replaced once the new config has listed its tools.
+func (g *Gateway) handleActivateServer(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Server string     `json:"server"`
+		Config UserConfig `json:"config"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Server == "" {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs a server name", activateServerToolName), nil), nil
+	}
+	if args.Config == nil {
+		args.Config = UserConfig{}
+	}
+
+	serverName, configKey, merged, rejection := g.prepareActivation(args.Server, args.Config)
+	if rejection != nil {
+		return rejection, nil
+	}
+	defer func() {
+		g.toolsLoading.Lock()
+		delete(g.activating, serverName)
+		g.toolsLoading.Unlock()
+	}()
+
+	tools, err := g.listServerTools(ctx, serverName, merged)
+	if err != nil {
+		return gatewayError(errCodeActivationFailed,
+			fmt.Sprintf("Failed to activate %q: %v", serverName, err),
+			map[string]any{"server": serverName}), nil
+	}
+
+	g.toolsLoading.Lock()
+	defer g.toolsLoading.Unlock()
+
+	// Replace the tools of a server that is already active
+	if _, active := g.userConfigs[configKey]; active {
+		g.deactivateServer(serverName, configKey)
+	}
+
+	servers := maps.Clone(g.catalog.Servers)
+	servers[serverName] = merged
+	userConfigs := maps.Clone(g.userConfigs)
+	if userConfigs == nil {
+		userConfigs = make(map[string]UserConfig)
+	}
+	userConfigs[configKey] = args.Config
+	g.setCatalogState(servers, userConfigs)
+	g.registerListedTools(serverName, merged, tools)
+
+	names := g.tools.namesForServer(serverName)
+	zap.L().Info("Server activated", zap.String("component", "TOOLS"), zap.String("server", serverName), zap.Int("tools", len(names)))
+
+	return structuredResult(map[string]any{"server": serverName, "configKey": configKey, "tools": names})
+}
+
+// prepareActivation checks an activation request and merges its config into a pristine copy of the
+// catalog server. The server is marked as activating until the caller removes it from g.activating.
+func (g *Gateway) prepareActivation(name string, config UserConfig) (string, string, catalog.Server, *mcp.CallToolResult) {
+	g.toolsLoading.Lock()
+	defer g.toolsLoading.Unlock()
+
+	serverName, configKey, ok := g.resolveCatalogServer(g.catalog.Servers, name)
+	if !ok {
+		return "", "", catalog.Server{}, unknownServerError(name)
+	}
+	if g.activating[serverName] {
+		return "", "", catalog.Server{}, gatewayError(errCodeInvalidArgs,
+			fmt.Sprintf("Server %q is already being activated", serverName),
+			map[string]any{"server": serverName})
+	}
+
+	pristine := g.pristine[serverName]
+	var missing []string
+	for _, key := range g.serverConfigKeys(serverName, configKey, pristine) {
+		if _, set := config[key.Key]; key.Required && !set {
+			missing = append(missing, key.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return "", "", catalog.Server{}, gatewayError(errCodeInvalidArgs,
+			fmt.Sprintf("Server %q needs the config keys: %s", serverName, strings.Join(missing, ", ")),
+			map[string]any{"server": serverName, "missing": missing})
+	}
+
+	merged := catalog.Catalog{Servers: map[string]catalog.Server{serverName: cloneServer(pristine)}}
+	if err := MergeUserConfigsIntoCatalog(merged, g.instructionMap, map[string]UserConfig{configKey: config}); err != nil {
+		return "", "", catalog.Server{}, gatewayError(errCodeInvalidArgs,
+			fmt.Sprintf("Invalid config for %q: %v", serverName, err),
+			map[string]any{"server": serverName})
+	}
+
+	g.activating[serverName] = true
+	return serverName, configKey, merged.Servers[serverName], nil
+}
+
+// listServerTools starts a server outside the session pool and lists its tools
+func (g *Gateway) listServerTools(ctx context.Context, serverName string, server catalog.Server) ([]*mcp.Tool, error) {
+	g.prePull.wait(ctx, serverName)
+
+	session, err := g.pool.createSession(ctx, serverName, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+	defer session.Close()
+
+	tools, err := session.ListTools(ctx, &mcp.ListToolsParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	return tools.Tools, nil
+}
+
+// handleDeactivateServer removes an active server's tools and closes its sessions
+func (g *Gateway) handleDeactivateServer(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Server string `json:"server"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Server == "" {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs a server name", deactivateServerToolName), nil), nil
+	}
+
+	g.toolsLoading.Lock()
+	defer g.toolsLoading.Unlock()
+
+	serverName, configKey, ok := g.resolveCatalogServer(g.catalog.Servers, args.Server)
+	if !ok {
+		return unknownServerError(args.Server), nil
+	}
+	if _, active := g.userConfigs[configKey]; !active {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("Server %q is not active", serverName), map[string]any{"server": serverName}), nil
+	}
+
+	removed := g.deactivateServer(serverName, configKey)
+
+	userConfigs := maps.Clone(g.userConfigs)
+	delete(userConfigs, configKey)
+	g.setCatalogState(g.catalog.Servers, userConfigs)
+
+	zap.L().Info("Server deactivated", zap.String("component", "TOOLS"), zap.String("server", serverName), zap.Int("tools", len(removed)))
+
+	return structuredResult(map[string]any{"server": serverName, "removedTools": removed})
+}
+
+// deactivateServer removes a server's tools, stops its background rediscovery and closes its sessions.
+// Callers must hold toolsLoading.
+func (g *Gateway) deactivateServer(serverName string, configKey string) []string {
+	removed := g.tools.removeServer(serverName)
+	if len(removed) > 0 && g.config.ToolMode != toolModeSearch {
+		g.server.RemoveTools(removed...)
+	}
+
+	g.discoveryMu.Lock()
+	delete(g.failedDiscoveries, serverName)
+	g.discoveryMu.Unlock()
+
+	g.lazyMu.Lock()
+	delete(g.lazyServers, serverName)
+	g.lazyMu.Unlock()
+
+	if err := g.pool.CloseServer(serverName); err != nil {
+		zap.L().Warn("Failed to close sessions of deactivated server",
+			zap.String("component", "TOOLS"),
+			zap.String("server", serverName),
+			zap.Error(err))
+	}
+
+	return removed
+}
+
+// unknownServerError reports a server that is not in the catalog
+func unknownServerError(name string) *mcp.CallToolResult {
+	return gatewayError(errCodeUnknownServer,
+		fmt.Sprintf("Unknown server %q. Use %s to find catalog servers.", name, listServersToolName),
+		map[string]any{"server": name})
+}
+
+// structuredResult returns a value both as structured content and as its JSON text
+func structuredResult(structured map[string]any) (*mcp.CallToolResult, error) {
+	text, err := json.Marshal(structured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: string(text)}},
+		StructuredContent: structured,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestServerListingDoesNotWaitForDiscovery(t *testing.T) {
+	g := &Gateway{
+		catalog:        catalog.Catalog{Servers: map[string]catalog.Server{"brave": {Title: "Brave Search"}}},
+		instructionMap: InstructionMap{"brave.api_key": {Server: "brave", Type: "secret", EnvName: "BRAVE_API_KEY"}},
+		userConfigs:    map[string]UserConfig{"brave": {}},
+	}
+
+	// A discovery pulling images holds toolsLoading for a long time
+	g.toolsLoading.Lock()
+	defer g.toolsLoading.Unlock()
+
+	for name, handler := range map[string]mcp.ToolHandler{
+		listServersToolName:  g.handleListServers,
+		serverConfigToolName: g.handleServerConfig,
+	} {
+		done := make(chan *mcp.CallToolResult, 1)
+		go func() {
+			result, _ := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: name, Arguments: []byte(`{"server": "brave"}`)}})
+			done <- result
+		}()
+
+		select {
+		case result := <-done:
+			if result == nil || result.IsError {
+				t.Errorf("%s failed: %v", name, result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s waited for the discovery", name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff describes an exponential delay between consecutive attempts
+type Backoff struct {
+	Initial    Duration `json:"initial,omitempty"`    // Delay before the second attempt
+	Max        Duration `json:"max,omitempty"`        // Upper bound for a single delay
+	Multiplier float64  `json:"multiplier,omitempty"` // Growth factor applied after every attempt
+	Jitter     float64  `json:"jitter,omitempty"`     // Random spread as a fraction of the delay (0.2 = ±20%)
+}
+
+// Delay returns how long to wait before the given retry (1 for the first retry)
+func (b Backoff) Delay(retry int) time.Duration {
+	delay := float64(b.Initial)
+	if delay <= 0 {
+		delay = float64(time.Second)
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+		if b.Max > 0 && delay >= float64(b.Max) {
+			delay = float64(b.Max)
+			break
+		}
+	}
+
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	// Spread retries of many callers so they don't hit a recovering backend at once
+	if b.Jitter > 0 {
+		jitter := min(b.Jitter, 1)
+		delay *= 1 - jitter + 2*jitter*rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// sleepContext waits for the given duration and reports false if the context was cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/sync/errgroup"
+)
+
+// batchToolName is the gateway-native tool that runs several tool calls in one request
+const batchToolName = "gateway-batch"
+
+// batchCall is a single entry of a batch request
+type batchCall struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// batchArguments are the arguments of the batch tool
+type batchArguments struct {
+	Calls       []batchCall `json:"calls"`
+	MaxParallel int         `json:"maxParallel,omitempty"`
+}
+
+// batchEntryResult is the outcome of a single batch entry
+type batchEntryResult struct {
+	Index             int           `json:"index"`
+	Tool              string        `json:"tool"`
+	IsError           bool          `json:"isError"`
+	Content           []mcp.Content `json:"content,omitempty"`
+	StructuredContent any           `json:"structuredContent,omitempty"`
+	Error             string        `json:"error,omitempty"`
+	Meta              mcp.Meta      `json:"_meta,omitempty"` // Error codes and gateway details of the entry
+}
+
+// batchTool returns the definition of the batch tool
+func batchTool(cfg BatchConfig) *mcp.Tool {
+	calls := map[string]any{
+		"type":     "array",
+		"minItems": 1,
+		"items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tool":      map[string]any{"type": "string", "description": "Name of the tool to call"},
+				"arguments": map[string]any{"type": "object", "description": "Arguments of the tool"},
+			},
+			"required": []string{"tool"},
+		},
+	}
+	if cfg.MaxCalls > 0 {
+		calls["maxItems"] = cfg.MaxCalls
+	}
+
+	return &mcp.Tool{
+		Name: batchToolName,
+		Description: fmt.Sprintf("Call several tools in one request. Calls run in parallel (up to %d at a time) "+
+			"and each entry gets its own result or error, in the order of the calls.", max(cfg.MaxParallel, 1)),
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"calls": calls,
+				"maxParallel": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Maximum calls running at once, capped by the gateway",
+				},
+			},
+			"required": []string{"calls"},
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"results": map[string]any{"type": "array"},
+			},
+			"required": []string{"results"},
+		},
+	}
+}
+
+// handleBatch runs the batch entries through the regular tool handlers
+func (g *Gateway) handleBatch(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := g.config.MetaTools.Batch
+
+	var args batchArguments
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("Invalid arguments for %s: %v", batchToolName, err), nil), nil
+	}
+	if len(args.Calls) == 0 {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs at least one call", batchToolName), nil), nil
+	}
+	if cfg.MaxCalls > 0 && len(args.Calls) > cfg.MaxCalls {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s accepts at most %d calls, got %d", batchToolName, cfg.MaxCalls, len(args.Calls)), nil), nil
+	}
+
+	parallel := max(cfg.MaxParallel, 1)
+	if args.MaxParallel > 0 && args.MaxParallel < parallel {
+		parallel = args.MaxParallel
+	}
+
+	results := make([]batchEntryResult, len(args.Calls))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(parallel)
+
+	for i, call := range args.Calls {
+		eg.Go(func() error {
+			results[i] = g.runBatchEntry(egCtx, req, i, call)
+			return nil
+		})
+	}
+	eg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.IsError {
+			failed++
+		}
+	}
+
+	structured := map[string]any{"results": results}
+	text, err := json.Marshal(structured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch results: %w", err)
+	}
+
+	// Each entry, structured content included, was limited by its tool handler. The combined result is limited
+	// like any other tool result, so its structured content is dropped or spilled once the results are too large.
+	return g.limitResult(ctx, req.Session, "", batchToolName, &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: string(text)}},
+		StructuredContent: structured,
+		Meta:              mcp.Meta{gatewayMetaKey: map[string]any{"calls": len(results), "failed": failed}},
+	}), nil
+}
+
+// runBatchEntry executes a single batch entry and converts its outcome into an entry result
+func (g *Gateway) runBatchEntry(ctx context.Context, req *mcp.CallToolRequest, index int, call batchCall) batchEntryResult {
+	entry := batchEntryResult{Index: index, Tool: call.Tool}
+
+	result, err := g.callRegisteredTool(ctx, req, call.Tool, call.Arguments)
+	if err != nil {
+		entry.IsError = true
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.IsError = result.IsError
+	entry.Content = result.Content
+	entry.StructuredContent = result.StructuredContent
+	entry.Meta = result.Meta
+	return entry
+}
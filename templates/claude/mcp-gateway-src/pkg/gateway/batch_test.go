@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestBatchDoesNotPassOversizedStructuredContent(t *testing.T) {
+	g := newLimitedGateway(overflowTruncate)
+	g.config.Results.MaxBytes = 1000
+	g.config.Results.Tools = map[string]ResultLimit{batchToolName: {MaxBytes: 1500}}
+	g.tools = newToolRegistry()
+	for name, size := range map[string]int{"sqlite-query": 2000, "sqlite-count": 300} {
+		g.tools.add(&registeredTool{
+			serverName: "sqlite",
+			tool:       &mcp.Tool{Name: name},
+			handler: func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return g.limitResult(ctx, req.Session, "sqlite", name, &mcp.CallToolResult{
+					Content:           []mcp.Content{&mcp.TextContent{Text: name}},
+					StructuredContent: map[string]any{"rows": strings.Repeat("x", size)},
+				}), nil
+			},
+		})
+	}
+
+	call := func(tools ...string) *mcp.CallToolResult {
+		t.Helper()
+		calls := make([]batchCall, 0, len(tools))
+		for _, tool := range tools {
+			calls = append(calls, batchCall{Tool: tool})
+		}
+		arguments, _ := json.Marshal(batchArguments{Calls: calls})
+		result, err := g.handleBatch(t.Context(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: batchToolName, Arguments: arguments}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// The oversized entry lost its structured content in its own handler, the small one kept it
+	result := call("sqlite-query", "sqlite-count")
+	results := result.StructuredContent.(map[string]any)["results"].([]batchEntryResult)
+	if results[0].StructuredContent != nil {
+		t.Error("oversized entry passed its structured content into the batch")
+	}
+	if results[1].StructuredContent == nil {
+		t.Error("structured content within the limit was dropped")
+	}
+
+	// Entries within their limits can still add up to a batch above it
+	result = call("sqlite-count", "sqlite-count", "sqlite-count", "sqlite-count")
+	if result.StructuredContent != nil {
+		t.Error("oversized batch kept its structured content")
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; len(text) > 1500 {
+		t.Errorf("batch text of %d bytes wasn't truncated", len(text))
+	}
+}
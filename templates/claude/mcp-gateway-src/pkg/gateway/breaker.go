@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// circuitBreaker stops sending tool calls to a server after repeated failures
+type circuitBreaker struct {
+	mu        sync.Mutex
+	cfg       CircuitBreakerConfig
+	state     string
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the breaker last opened
+	probing   bool      // Whether a half-open trial call is in flight
+	lastError string
+}
+
+// BreakerStatus describes the circuit breaker of a server for the status endpoint
+type BreakerStatus struct {
+	Server    string    `json:"server"`
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`
+	OpenedAt  time.Time `json:"openedAt,omitzero"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// allow reports whether a call may proceed, and if not, how long until the next trial call
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		remaining := time.Until(b.openedAt.Add(time.Duration(b.cfg.Cooldown)))
+		if remaining > 0 {
+			return false, remaining
+		}
+		// Cooldown is over, let a single trial call through
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true, 0
+	case breakerHalfOpen:
+		if b.probing {
+			return false, time.Duration(b.cfg.Cooldown)
+		}
+		b.probing = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// record updates the breaker with the outcome of a call that was allowed through
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.lastError = err.Error()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= max(b.cfg.FailureThreshold, 1) {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
+
+// abandon releases a trial call without counting its outcome
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// status returns a snapshot of the breaker
+func (b *circuitBreaker) status(serverName string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerStatus{
+		Server:    serverName,
+		State:     b.state,
+		Failures:  b.failures,
+		OpenedAt:  b.openedAt,
+		LastError: b.lastError,
+	}
+}
+
+// breakerRegistry lazily creates one circuit breaker per server
+type breakerRegistry struct {
+	mu       sync.Mutex
+	cfg      CircuitBreakerConfig
+	breakers map[string]*circuitBreaker
+}
+
+// newBreakerRegistry creates an empty registry using the given settings for new breakers
+func newBreakerRegistry(cfg CircuitBreakerConfig) *breakerRegistry {
+	return &breakerRegistry{
+		cfg:      cfg,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// get returns the breaker of a server, creating it on first use
+func (r *breakerRegistry) get(serverName string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[serverName]
+	if !ok {
+		b = &circuitBreaker{cfg: r.cfg, state: breakerClosed}
+		r.breakers[serverName] = b
+	}
+	return b
+}
+
+// status returns the state of every breaker that has seen a call
+func (r *breakerRegistry) status() []BreakerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(r.breakers))
+	for name, b := range r.breakers {
+		statuses = append(statuses, b.status(name))
+	}
+	return statuses
+}
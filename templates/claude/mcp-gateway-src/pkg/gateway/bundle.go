@@ -0,0 +1,372 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"go.uber.org/zap"
+)
+
+// bundleManifestName is the tarball entry holding the bundle manifest.
+// The other entries are the output of docker save, so a bundle can also be loaded with docker load.
+const bundleManifestName = "mcp-gateway-bundle.json"
+
+// bundleManifest lists the images of a bundle
+type bundleManifest struct {
+	Created time.Time     `json:"created"`
+	Images  []bundleImage `json:"images"`
+}
+
+// bundleImage is one server image of a bundle
+type bundleImage struct {
+	Server string `json:"server"`           // Catalog server name
+	Image  string `json:"image"`            // Reference in the catalog
+	Digest string `json:"digest,omitempty"` // Repository digest the catalog pins or the registry reported
+	Tag    string `json:"tag"`              // Reference the image is saved under
+	ID     string `json:"id"`               // Image ID, the digest of the config or with the containerd store of the manifest
+
+	// RepoDigestChecked records that the daemon listed the pinned digest among the repository digests
+	// of the image at export, for archives of the classic image store that lack the pinned manifest
+	RepoDigestChecked bool `json:"repoDigestChecked,omitempty"`
+}
+
+// ExportBundle resolves beautified service names like PullImages, makes their images available
+// according to the images settings of configJSON and writes them with a manifest into one tarball.
+// Only the Docker runtime can save images.
+func ExportBundle(ctx context.Context, runtime containers.Runtime, catalogPaths []string, mappingPath string, configJSON []byte, beautifiedNames []string, outputPath string) error {
+	engine, ok := containers.EngineClient(runtime)
+	if !ok {
+		return fmt.Errorf("bundles need the %s runtime, %s is not supported", containers.DockerName, runtime.Name())
+	}
+
+	servers, skipped, gatewayConfig, err := resolveImageServers(ctx, catalogPaths, mappingPath, configJSON, ServerSelection{Names: beautifiedNames})
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		zap.L().Info("Skipping service without image",
+			zap.String("component", "BUNDLE"),
+			zap.String("server", s.name),
+			zap.String("reason", skipReason(s.server)))
+	}
+	if len(servers) == 0 {
+		return fmt.Errorf("none of the services runs an image")
+	}
+
+	manifest := bundleManifest{Created: time.Now().UTC()}
+	pins := make(map[string]string)
+	var tags []string
+	seen := make(map[string]bool)
+	for _, s := range servers {
+		entry, err := bundleImageOf(ctx, engine, runtime, s, gatewayConfig.ImagesFor(s.name))
+		if err != nil {
+			return err
+		}
+		manifest.Images = append(manifest.Images, entry)
+		if pins[s.name], err = imageDigest(s.server.Image); err != nil {
+			return err
+		}
+		if !seen[entry.Tag] {
+			seen[entry.Tag] = true
+			tags = append(tags, entry.Tag)
+		}
+	}
+
+	saved, err := engine.ImageSave(ctx, tags)
+	if err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	defer saved.Close()
+
+	if err := writeBundle(outputPath, manifest, saved, pins); err != nil {
+		return err
+	}
+
+	zap.L().Info("Bundle exported",
+		zap.String("component", "BUNDLE"),
+		zap.String("path", outputPath),
+		zap.Int("images", len(tags)))
+	return nil
+}
+
+// bundleImageOf makes the image of a server available and tags it under its bundle tag
+func bundleImageOf(ctx context.Context, engine *client.Client, runtime containers.Runtime, s imageServer, images transport.Images) (bundleImage, error) {
+	local, err := transport.EnsureImage(ctx, runtime, s.server.Image, images)
+	if err != nil {
+		return bundleImage{}, fmt.Errorf("failed to pull image %q: %w", s.server.Image, err)
+	}
+
+	inspect, err := engine.ImageInspect(ctx, local)
+	if err != nil {
+		return bundleImage{}, fmt.Errorf("failed to inspect image %q: %w", local, err)
+	}
+
+	tag, err := transport.BundleTag(s.server.Image)
+	if err != nil {
+		return bundleImage{}, err
+	}
+	if tag != local {
+		if err := engine.ImageTag(ctx, inspect.ID, tag); err != nil {
+			return bundleImage{}, fmt.Errorf("failed to tag image %q: %w", s.server.Image, err)
+		}
+	}
+
+	digest, err := imageDigest(s.server.Image)
+	if err != nil {
+		return bundleImage{}, err
+	}
+	repoDigestChecked := false
+	if digest != "" {
+		if !slices.ContainsFunc(inspect.RepoDigests, func(repoDigest string) bool { return strings.HasSuffix(repoDigest, "@"+digest) }) {
+			return bundleImage{}, fmt.Errorf("image %q of %q doesn't have the pinned digest, its repository digests are %v", local, s.name, inspect.RepoDigests)
+		}
+		repoDigestChecked = true
+	}
+	if digest == "" && len(inspect.RepoDigests) > 0 {
+		// Unpinned catalog references record the digest the registry reported
+		if named, err := reference.ParseNormalizedNamed(inspect.RepoDigests[0]); err == nil {
+			if digested, ok := named.(reference.Digested); ok {
+				digest = digested.Digest().String()
+			}
+		}
+	}
+
+	return bundleImage{
+		Server: s.name,
+		Image:  s.server.Image,
+		Digest: digest,
+		Tag:    tag,
+		ID:     inspect.ID,
+
+		RepoDigestChecked: repoDigestChecked,
+	}, nil
+}
+
+// writeBundle writes the manifest followed by the entries of a docker save archive.
+// The bundle is written next to outputPath and renamed into place once complete and verified
+// like ImportBundle will, so that a bundle that can't be imported is never written.
+func writeBundle(outputPath string, manifest bundleManifest, saved io.Reader, pins map[string]string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), ".bundle-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	tw := tar.NewWriter(tmp)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    bundleManifestName,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: manifest.Created,
+	}); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	archive := newSavedArchive()
+	tr := tar.NewReader(saved)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read saved images: %w", err)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if err := archive.add(header, io.TeeReader(tr, tw)); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		// Entries that aren't regular files are not hashed but still have to be written
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+
+	if err := archive.checkBlobs(); err != nil {
+		return err
+	}
+	if err := verifyBundleArchive(manifest, archive, pins); err != nil {
+		return fmt.Errorf("the saved images don't match the bundle: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return os.Rename(tmp.Name(), outputPath)
+}
+
+// ImportBundle loads the images of a bundle after checking that its manifest matches the catalog
+// and that the saved images match its manifest, digests computed from their content.
+// Loaded images that still end up with another ID are untagged again. Only the Docker runtime can load images.
+func ImportBundle(ctx context.Context, runtime containers.Runtime, catalogPaths []string, bundlePath string) error {
+	engine, ok := containers.EngineClient(runtime)
+	if !ok {
+		return fmt.Errorf("bundles need the %s runtime, %s is not supported", containers.DockerName, runtime.Name())
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	manifest, err := readBundleManifest(f)
+	if err != nil {
+		return err
+	}
+
+	cat, err := catalog.ReadFrom(ctx, catalogPaths)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	if err := verifyBundleCatalog(manifest, cat); err != nil {
+		return err
+	}
+
+	pins := make(map[string]string)
+	for _, entry := range manifest.Images {
+		if pins[entry.Server], err = imageDigest(cat.Servers[entry.Server].Image); err != nil {
+			return err
+		}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	archive, err := readSavedArchive(f)
+	if err != nil {
+		return err
+	}
+	if err := verifyBundleArchive(manifest, archive, pins); err != nil {
+		return fmt.Errorf("refusing to load bundle: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	loaded, err := engine.ImageLoad(ctx, f, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	defer loaded.Body.Close()
+	if err := jsonmessage.DisplayJSONMessagesStream(loaded.Body, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+
+	var errs []error
+	for _, entry := range manifest.Images {
+		inspect, err := engine.ImageInspect(ctx, entry.Tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("image %q of %q was not loaded: %w", entry.Tag, entry.Server, err))
+			continue
+		}
+		// The daemon may report the ID of either image store, both were computed from the archive
+		if _, ids, _ := archive.imageIDs(entry.Tag); !slices.Contains(ids, inspect.ID) {
+			errs = append(errs, fmt.Errorf("image %q of %q has ID %s, the bundle holds %s", entry.Tag, entry.Server, inspect.ID, strings.Join(ids, " or ")))
+			if _, err := engine.ImageRemove(ctx, entry.Tag, image.RemoveOptions{}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to untag image %q: %w", entry.Tag, err))
+			}
+			continue
+		}
+		zap.L().Info("Image imported",
+			zap.String("component", "BUNDLE"),
+			zap.String("server", entry.Server),
+			zap.String("image", entry.Image))
+	}
+	return errors.Join(errs...)
+}
+
+// readBundleManifest reads the manifest entry of a bundle
+func readBundleManifest(r io.Reader) (bundleManifest, error) {
+	var manifest bundleManifest
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return manifest, fmt.Errorf("not a bundle: %s is missing", bundleManifestName)
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Name != bundleManifestName {
+			continue
+		}
+
+		var data bytes.Buffer
+		if _, err := io.Copy(&data, tr); err != nil {
+			return manifest, fmt.Errorf("failed to read bundle manifest: %w", err)
+		}
+		if err := json.Unmarshal(data.Bytes(), &manifest); err != nil {
+			return manifest, fmt.Errorf("invalid bundle manifest: %w", err)
+		}
+		return manifest, nil
+	}
+}
+
+// verifyBundleCatalog checks that every bundled image is the one the catalog expects for its server
+func verifyBundleCatalog(manifest bundleManifest, cat catalog.Catalog) error {
+	var errs []error
+	for _, entry := range manifest.Images {
+		server, ok := cat.Servers[entry.Server]
+		if !ok {
+			errs = append(errs, fmt.Errorf("server %q of the bundle is not in the catalog", entry.Server))
+			continue
+		}
+
+		digest, err := imageDigest(server.Image)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch {
+		case digest != "" && digest != entry.Digest:
+			errs = append(errs, fmt.Errorf("server %q: catalog pins digest %s, the bundle has %s", entry.Server, digest, entry.Digest))
+		case digest == "" && server.Image != entry.Image:
+			errs = append(errs, fmt.Errorf("server %q: catalog image is %q, the bundle has %q", entry.Server, server.Image, entry.Image))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// imageDigest returns the digest a reference pins, empty for references by tag
+func imageDigest(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return digested.Digest().String(), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,308 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+)
+
+// maxArchiveMetadata bounds the entries of a saved archive that are kept in memory to be parsed:
+// manifests, indexes and image configs. Layers are only hashed.
+const maxArchiveMetadata = 4 << 20
+
+// Entries of a docker save archive
+const (
+	savedManifestName = "manifest.json" // Images and tags for docker load
+	savedIndexName    = "index.json"    // OCI image layout index, written by Docker 25 and later
+	savedBlobsDir     = "blobs/"        // Content-addressed blobs of the OCI image layout
+)
+
+// containerdImageName is the index annotation docker load with the containerd image store tags images with
+const containerdImageName = "io.containerd.image.name"
+
+// savedArchive is what a docker save archive contains, with the digest of every entry computed
+// while reading it rather than trusted from its name
+type savedArchive struct {
+	digests  map[string]digest.Digest // Computed digest by entry name
+	metadata map[string][]byte        // Content of the small entries by entry name
+}
+
+// savedImage is an entry of the manifest.json of a docker save archive
+type savedImage struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// savedDescriptor is a manifest, manifest list or index, Docker's and OCI's share these fields
+type savedDescriptor struct {
+	Config *struct {
+		Digest digest.Digest `json:"digest"`
+	} `json:"config,omitempty"`
+	Manifests []struct {
+		Digest      digest.Digest     `json:"digest"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"manifests,omitempty"`
+}
+
+// newSavedArchive returns an empty archive to be filled with add
+func newSavedArchive() *savedArchive {
+	return &savedArchive{
+		digests:  make(map[string]digest.Digest),
+		metadata: make(map[string][]byte),
+	}
+}
+
+// readSavedArchive reads every entry of a tar stream, skipping the bundle manifest
+func readSavedArchive(r io.Reader) (*savedArchive, error) {
+	archive := newSavedArchive()
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return archive, archive.checkBlobs()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Name == bundleManifestName {
+			continue
+		}
+		if err := archive.add(header, tr); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// add hashes an archive entry, keeping its content when it's small enough to be metadata
+func (a *savedArchive) add(header *tar.Header, content io.Reader) error {
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	name := path.Clean(header.Name)
+
+	digester := digest.Canonical.Digester()
+	var kept bytes.Buffer
+	w := io.Writer(digester.Hash())
+	if header.Size <= maxArchiveMetadata {
+		w = io.MultiWriter(w, &kept)
+	}
+	if _, err := io.Copy(w, content); err != nil {
+		return fmt.Errorf("failed to read %s of the saved images: %w", name, err)
+	}
+
+	a.digests[name] = digester.Digest()
+	if header.Size <= maxArchiveMetadata {
+		a.metadata[name] = kept.Bytes()
+	}
+	return nil
+}
+
+// checkBlobs verifies that every blob has the digest its name claims
+func (a *savedArchive) checkBlobs() error {
+	for name, computed := range a.digests {
+		if !strings.HasPrefix(name, savedBlobsDir) {
+			continue
+		}
+		claimed := digest.NewDigestFromEncoded(digest.Algorithm(path.Base(path.Dir(name))), path.Base(name))
+		if claimed.Validate() == nil && claimed != computed {
+			return fmt.Errorf("blob %s of the saved images has digest %s", name, computed)
+		}
+	}
+	return nil
+}
+
+// blob returns the content of a blob, which checkBlobs verified
+func (a *savedArchive) blob(d digest.Digest) ([]byte, bool) {
+	if d.Validate() != nil {
+		return nil, false
+	}
+	data, ok := a.metadata[savedBlobsDir+d.Algorithm().String()+"/"+d.Encoded()]
+	return data, ok
+}
+
+// images returns the entries of manifest.json
+func (a *savedArchive) images() ([]savedImage, error) {
+	data, ok := a.metadata[savedManifestName]
+	if !ok {
+		return nil, fmt.Errorf("the saved images have no %s", savedManifestName)
+	}
+	var images []savedImage
+	if err := json.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("invalid %s in the saved images: %w", savedManifestName, err)
+	}
+	return images, nil
+}
+
+// index returns the OCI index of the archive, nil for archives of Docker before 25
+func (a *savedArchive) index() (*savedDescriptor, error) {
+	data, ok := a.metadata[savedIndexName]
+	if !ok {
+		return nil, nil
+	}
+	var index savedDescriptor
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid %s in the saved images: %w", savedIndexName, err)
+	}
+	return &index, nil
+}
+
+// imageIDs returns the IDs docker load may give the image tagged tag: the digest of its config,
+// and with the containerd image store the digest of the manifest or index the tag points at
+func (a *savedArchive) imageIDs(tag string) (config string, ids []string, err error) {
+	images, err := a.images()
+	if err != nil {
+		return "", nil, err
+	}
+	want := normalizedName(tag)
+	for _, image := range images {
+		if !slices.ContainsFunc(image.RepoTags, func(repoTag string) bool { return normalizedName(repoTag) == want }) {
+			continue
+		}
+		id, ok := a.digests[path.Clean(image.Config)]
+		if !ok {
+			return "", nil, fmt.Errorf("config %s of %s is missing from the saved images", image.Config, tag)
+		}
+		config = id.String()
+		break
+	}
+	if config == "" {
+		return "", nil, fmt.Errorf("%s is not among the saved images", tag)
+	}
+	ids = []string{config}
+
+	index, err := a.index()
+	if err != nil || index == nil {
+		return config, ids, err
+	}
+	for _, m := range index.Manifests {
+		if normalizedName(m.Annotations[containerdImageName]) != want {
+			continue
+		}
+		if !a.reaches(m.Digest, config) {
+			return "", nil, fmt.Errorf("%s of %s doesn't lead to its config %s in the saved images", m.Digest, tag, config)
+		}
+		ids = append(ids, m.Digest.String())
+	}
+	return config, ids, nil
+}
+
+// tags returns every name docker load would tag an image with
+func (a *savedArchive) tags() ([]string, error) {
+	images, err := a.images()
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, image := range images {
+		tags = append(tags, image.RepoTags...)
+	}
+
+	index, err := a.index()
+	if err != nil || index == nil {
+		return tags, err
+	}
+	for _, m := range index.Manifests {
+		if name := m.Annotations[containerdImageName]; name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags, nil
+}
+
+// reaches reports whether the manifest or index pinned by a digest refers to the image config id,
+// following blobs of the archive only
+func (a *savedArchive) reaches(pin digest.Digest, id string) bool {
+	return a.reachesFrom(pin, id, make(map[digest.Digest]bool))
+}
+
+// reachesFrom is reaches, skipping the manifests already visited
+func (a *savedArchive) reachesFrom(pin digest.Digest, id string, visited map[digest.Digest]bool) bool {
+	if visited[pin] {
+		return false
+	}
+	visited[pin] = true
+
+	data, ok := a.blob(pin)
+	if !ok {
+		return false
+	}
+	var descriptor savedDescriptor
+	if err := json.Unmarshal(data, &descriptor); err != nil {
+		return false
+	}
+	if descriptor.Config != nil && descriptor.Config.Digest.String() == id {
+		return true
+	}
+	for _, m := range descriptor.Manifests {
+		if a.reachesFrom(m.Digest, id, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyBundleArchive checks the saved images of a bundle against its manifest before they're loaded:
+// every image has the recorded ID computed from the archive, images pinned by digest are reachable
+// from the pinned manifest when the archive holds it, and no image is tagged with a name outside the bundle.
+// pins holds the digest the catalog pins by server name.
+func verifyBundleArchive(manifest bundleManifest, archive *savedArchive, pins map[string]string) error {
+	allowed := make(map[string]bool)
+	var errs []error
+	for _, entry := range manifest.Images {
+		allowed[normalizedName(entry.Tag)] = true
+
+		config, ids, err := archive.imageIDs(entry.Tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("server %q: %w", entry.Server, err))
+			continue
+		}
+		if !slices.Contains(ids, entry.ID) {
+			errs = append(errs, fmt.Errorf("server %q: image %q has ID %s in the saved images, the bundle recorded %s", entry.Server, entry.Tag, ids[len(ids)-1], entry.ID))
+			continue
+		}
+
+		pin := pins[entry.Server]
+		if pin == "" {
+			continue
+		}
+		// Only the containerd image store saves the manifest the registry served, the classic
+		// store saves a generated one or none, which leaves the check done at export
+		if _, ok := archive.blob(digest.Digest(pin)); ok {
+			if !archive.reaches(digest.Digest(pin), config) {
+				errs = append(errs, fmt.Errorf("server %q: manifest %s of the saved images doesn't lead to image config %s", entry.Server, pin, config))
+			}
+		} else if !entry.RepoDigestChecked {
+			errs = append(errs, fmt.Errorf("server %q: the saved images don't contain manifest %s and the export didn't check it", entry.Server, pin))
+		}
+	}
+
+	tags, err := archive.tags()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, tag := range tags {
+		if !allowed[normalizedName(tag)] {
+			errs = append(errs, fmt.Errorf("the saved images tag %q, which is not an image of the bundle", tag))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// normalizedName returns the fully qualified form of an image reference, the reference itself when invalid
+func normalizedName(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return reference.TagNameOnly(named).String()
+}
@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/opencontainers/go-digest"
+)
+
+// Layouts of docker save archives
+const (
+	containerdLayout = "containerd" // Docker 25+ with the containerd image store: the registry's index is kept
+	classicLayout    = "classic"    // Docker 25+ with the classic store: an OCI layout with a generated manifest
+	legacyLayout     = "legacy"     // Docker before 25: manifest.json, configs and layer directories only
+)
+
+// testArchive builds a docker save archive of one image in one of the layouts
+type testArchive struct {
+	entries    map[string][]byte
+	tags       []string // RepoTags of manifest.json
+	configPath string
+
+	config digest.Digest
+	index  digest.Digest // Image ID with the containerd store
+	pin    digest.Digest // Digest of the index in the registry
+}
+
+func newTestArchive(tag, layout string) *testArchive {
+	a := &testArchive{entries: make(map[string][]byte), tags: []string{tag}}
+	config := `{"architecture":"amd64","os":"linux","rootfs":{"type":"layers","diff_ids":[]}}`
+
+	if layout == legacyLayout {
+		a.config = digest.FromString(config)
+		a.configPath = a.config.Encoded() + ".json"
+		a.entries[a.configPath] = []byte(config)
+		a.entries[digest.FromString("layer").Encoded()+"/layer.tar"] = []byte("layer")
+		a.pin = digest.FromString("registry index")
+		return a
+	}
+
+	a.config = a.addBlob(config)
+	a.configPath = "blobs/sha256/" + a.config.Encoded()
+	layer := a.addBlob("layer")
+	manifest := a.addBlob(`{"schemaVersion":2,"config":{"digest":"` + a.config.String() + `"},"layers":[{"digest":"` + layer.String() + `"}]}`)
+	target := manifest
+	if layout == containerdLayout {
+		a.index = a.addBlob(`{"schemaVersion":2,"manifests":[{"digest":"` + manifest.String() + `"}]}`)
+		a.pin = a.index
+		target = a.index
+	} else {
+		a.pin = digest.FromString("registry index")
+	}
+	a.entries["index.json"] = []byte(`{"schemaVersion":2,"manifests":[{"digest":"` + target.String() + `","annotations":{"io.containerd.image.name":"docker.io/` + tag + `"}}]}`)
+	return a
+}
+
+func (a *testArchive) addBlob(content string) digest.Digest {
+	d := digest.FromString(content)
+	a.entries["blobs/sha256/"+d.Encoded()] = []byte(content)
+	return d
+}
+
+// tar writes the archive, manifest.json last so tests can change its tags
+func (a *testArchive) tar(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	saved, err := json.Marshal([]savedImage{{Config: a.configPath, RepoTags: a.tags}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	write := func(name string, content []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(content)
+	}
+	for name, content := range a.entries {
+		write(name, content)
+	}
+	write("manifest.json", saved)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestVerifyBundleArchive(t *testing.T) {
+	const tag = "mcp/fetch:sha256-abc"
+
+	tests := []struct {
+		name    string
+		layout  string
+		modify  func(a *testArchive, entry *bundleImage, pins map[string]string)
+		wantErr string
+	}{
+		{"config ID", containerdLayout, nil, ""},
+		{"containerd store ID", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			entry.ID = a.index.String()
+		}, ""},
+		{"unpinned", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			delete(pins, "fetch")
+		}, ""},
+		{"recorded ID differs", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			entry.ID = digest.FromString("other").String()
+		}, "the bundle recorded"},
+		{"pinned manifest missing", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			pins["fetch"] = digest.FromString("pinned elsewhere").String()
+			entry.RepoDigestChecked = false
+		}, "don't contain manifest"},
+		{"pinned manifest leads elsewhere", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			pins["fetch"] = a.addBlob(`{"schemaVersion":2,"config":{"digest":"` + digest.FromString("other config").String() + `"}}`).String()
+		}, "doesn't lead to image config"},
+		{"foreign tag", containerdLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			a.tags = append(a.tags, "alpine:latest")
+		}, `tag "alpine:latest"`},
+		{"classic store checked at export", classicLayout, nil, ""},
+		{"classic store unchecked", classicLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			entry.RepoDigestChecked = false
+		}, "export didn't check it"},
+		{"before Docker 25 checked at export", legacyLayout, nil, ""},
+		{"before Docker 25 unchecked", legacyLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			entry.RepoDigestChecked = false
+		}, "export didn't check it"},
+		{"before Docker 25 recorded ID differs", legacyLayout, func(a *testArchive, entry *bundleImage, pins map[string]string) {
+			entry.ID = digest.FromString("other").String()
+		}, "the bundle recorded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestArchive(tag, tt.layout)
+			entry := bundleImage{Server: "fetch", Image: "mcp/fetch@" + a.pin.String(), Tag: tag, ID: a.config.String(), RepoDigestChecked: true}
+			pins := map[string]string{"fetch": a.pin.String()}
+			if tt.modify != nil {
+				tt.modify(a, &entry, pins)
+			}
+
+			archive, err := readSavedArchive(a.tar(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = verifyBundleArchive(bundleManifest{Images: []bundleImage{entry}}, archive, pins)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadSavedArchiveRejectsTamperedBlobs(t *testing.T) {
+	a := newTestArchive("mcp/fetch:latest", containerdLayout)
+	a.entries["blobs/sha256/"+a.config.Encoded()] = []byte(`{"architecture":"amd64","os":"linux","config":{"Entrypoint":["/evil"]}}`)
+
+	if _, err := readSavedArchive(a.tar(t)); err == nil || !strings.Contains(err.Error(), "has digest") {
+		t.Errorf("got %v, want the tampered blob rejected", err)
+	}
+}
+
+func TestBundlesRejectRuntimesOtherThanDocker(t *testing.T) {
+	runtime := containers.NewFake()
+	if err := ExportBundle(t.Context(), runtime, nil, "", nil, []string{"fetch"}, t.TempDir()+"/bundle.tar"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("export: got %v, want the fake runtime rejected", err)
+	}
+	if err := ImportBundle(t.Context(), runtime, nil, t.TempDir()+"/bundle.tar"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("import: got %v, want the fake runtime rejected", err)
+	}
+}
@@ -0,0 +1,351 @@
+package gateway
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// resultCache caches tool results in memory and optionally on disk
+type resultCache struct {
+	mu      sync.Mutex
+	cfg     CacheConfig
+	entries map[string]*list.Element // of *cacheEntry, most recently used in front
+	lru     *list.List
+	bytes   int64
+
+	diskBytes int64
+	stats     map[string]*CacheServerStatus
+	evictions int
+}
+
+// cacheEntry is a serialized tool result
+type cacheEntry struct {
+	Key     string          `json:"key"`
+	Server  string          `json:"server"`
+	Expires time.Time       `json:"expires"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// CacheStatus describes the result cache for the status endpoint
+type CacheStatus struct {
+	Enabled   bool                          `json:"enabled"`
+	Entries   int                           `json:"entries"`
+	Bytes     int64                         `json:"bytes"`
+	DiskBytes int64                         `json:"diskBytes,omitempty"`
+	Evictions int                           `json:"evictions"`
+	Servers   map[string]*CacheServerStatus `json:"servers"`
+}
+
+// CacheServerStatus holds the cache counters of one server
+type CacheServerStatus struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// newResultCache creates a cache, loading the size of an existing on-disk cache
+func newResultCache(cfg CacheConfig) *resultCache {
+	c := &resultCache{
+		cfg:     cfg,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		stats:   make(map[string]*CacheServerStatus),
+	}
+
+	if cfg.Enabled && cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			zap.L().Warn("Disabling on-disk result cache", zap.String("component", "CACHE"), zap.String("dir", cfg.Dir), zap.Error(err))
+			c.cfg.Dir = ""
+		} else {
+			c.pruneDisk()
+		}
+	}
+
+	return c
+}
+
+// cacheKey identifies a call by server, tool and canonicalized arguments
+func cacheKey(serverName string, toolName string, arguments json.RawMessage) string {
+	canonical := []byte(arguments)
+
+	// Re-encoding sorts object keys and drops insignificant whitespace
+	var decoded any
+	if len(arguments) > 0 && json.Unmarshal(arguments, &decoded) == nil {
+		if encoded, err := json.Marshal(decoded); err == nil {
+			canonical = encoded
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(serverName))
+	h.Write([]byte{0})
+	h.Write([]byte(toolName))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable reports whether results of the tool may be cached
+func cacheable(tool *mcp.Tool) bool {
+	return tool != nil && tool.Annotations != nil && (tool.Annotations.ReadOnlyHint || tool.Annotations.IdempotentHint)
+}
+
+// cacheBypassed reports whether the caller asked to skip the cache via _meta
+func cacheBypassed(params *mcp.CallToolParamsRaw) bool {
+	meta, ok := params.Meta[gatewayMetaKey].(map[string]any)
+	if !ok {
+		return false
+	}
+	mode, _ := meta["cache"].(string)
+	return mode == "bypass" || mode == "no-cache"
+}
+
+// get returns a cached result that has not expired yet
+func (c *resultCache) get(serverName string, key string) (*mcp.CallToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.serverStats(serverName)
+	entry, ok := c.lookup(key)
+	if !ok {
+		stats.Misses++
+		return nil, false
+	}
+
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(entry.Result, &result); err != nil {
+		c.remove(key)
+		stats.Misses++
+		return nil, false
+	}
+
+	stats.Hits++
+	return &result, true
+}
+
+// lookup finds a live entry in memory or on disk, must be called with the lock held
+func (c *resultCache) lookup(key string) (*cacheEntry, bool) {
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if time.Now().After(entry.Expires) {
+			c.remove(key)
+			return nil, false
+		}
+		c.lru.MoveToFront(elem)
+		return entry, true
+	}
+
+	if c.cfg.Dir == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Now().After(entry.Expires) {
+		c.removeDisk(key, int64(len(data)))
+		return nil, false
+	}
+
+	c.store(&entry)
+	return &entry, true
+}
+
+// put stores a result for the given time to live
+func (c *resultCache) put(serverName string, key string, result *mcp.CallToolResult, ttl time.Duration) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.MaxBytes > 0 && int64(len(data)) > c.cfg.MaxBytes {
+		return // Would evict everything else
+	}
+
+	entry := &cacheEntry{Key: key, Server: serverName, Expires: time.Now().Add(ttl), Result: data}
+	c.remove(key)
+	c.store(entry)
+
+	if c.cfg.Dir != "" {
+		c.writeDisk(entry)
+	}
+}
+
+// store adds an entry to memory and evicts the least recently used ones over the limits
+func (c *resultCache) store(entry *cacheEntry) {
+	if elem, ok := c.entries[entry.Key]; ok {
+		c.bytes -= int64(len(elem.Value.(*cacheEntry).Result))
+		c.lru.Remove(elem)
+	}
+
+	c.entries[entry.Key] = c.lru.PushFront(entry)
+	c.bytes += int64(len(entry.Result))
+
+	for c.lru.Len() > 1 && ((c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries) || (c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)) {
+		oldest := c.lru.Back().Value.(*cacheEntry)
+		c.lru.Remove(c.lru.Back())
+		delete(c.entries, oldest.Key)
+		c.bytes -= int64(len(oldest.Result))
+		c.evictions++
+	}
+}
+
+// remove drops an entry from memory and disk
+func (c *resultCache) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.bytes -= int64(len(elem.Value.(*cacheEntry).Result))
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	if c.cfg.Dir != "" {
+		if info, err := os.Stat(c.diskPath(key)); err == nil {
+			c.removeDisk(key, info.Size())
+		}
+	}
+}
+
+// diskPath returns the file holding an on-disk entry
+func (c *resultCache) diskPath(key string) string {
+	return filepath.Join(c.cfg.Dir, key+".json")
+}
+
+// writeDisk persists an entry and keeps the directory within the size limit
+func (c *resultCache) writeDisk(entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	// Write atomically so concurrent gateways never read a partial entry
+	tmp, err := os.CreateTemp(c.cfg.Dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil || os.Rename(tmp.Name(), c.diskPath(entry.Key)) != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	c.diskBytes += int64(len(data))
+	if c.cfg.MaxDiskBytes > 0 && c.diskBytes > c.cfg.MaxDiskBytes {
+		c.pruneDisk()
+	}
+}
+
+// removeDisk deletes an on-disk entry
+func (c *resultCache) removeDisk(key string, size int64) {
+	if os.Remove(c.diskPath(key)) == nil {
+		c.diskBytes -= size
+	}
+}
+
+// pruneDisk deletes expired entries and then the oldest ones until the directory fits the size limit
+func (c *resultCache) pruneDisk() {
+	dirEntries, err := os.ReadDir(c.cfg.Dir)
+	if err != nil {
+		return
+	}
+
+	type diskFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []diskFile
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(c.cfg.Dir, dirEntry.Name())
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+
+		var entry cacheEntry
+		if data, err := os.ReadFile(path); err != nil || json.Unmarshal(data, &entry) != nil || time.Now().After(entry.Expires) {
+			os.Remove(path)
+			continue
+		}
+
+		files = append(files, diskFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	if c.cfg.MaxDiskBytes > 0 && total > c.cfg.MaxDiskBytes {
+		sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+		for _, f := range files {
+			if total <= c.cfg.MaxDiskBytes {
+				break
+			}
+			if os.Remove(f.path) == nil {
+				total -= f.size
+			}
+		}
+	}
+
+	c.diskBytes = total
+}
+
+// serverStats returns the counters of a server, must be called with the lock held
+func (c *resultCache) serverStats(serverName string) *CacheServerStatus {
+	stats, ok := c.stats[serverName]
+	if !ok {
+		stats = &CacheServerStatus{}
+		c.stats[serverName] = stats
+	}
+	return stats
+}
+
+// status returns a snapshot of the cache
+func (c *resultCache) status() CacheStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	servers := make(map[string]*CacheServerStatus, len(c.stats))
+	for name, stats := range c.stats {
+		copied := *stats
+		servers[name] = &copied
+	}
+
+	return CacheStatus{
+		Enabled:   c.cfg.Enabled,
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+		DiskBytes: c.diskBytes,
+		Evictions: c.evictions,
+		Servers:   servers,
+	}
+}
+
+// setGatewayMeta adds a key to the gateway's _meta section of a result
+func setGatewayMeta(result *mcp.CallToolResult, key string, value any) {
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	meta, ok := result.Meta[gatewayMetaKey].(map[string]any)
+	if !ok {
+		meta = map[string]any{}
+		result.Meta[gatewayMetaKey] = meta
+	}
+	meta[key] = value
+}
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"context"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
+)
+
+// CleanupContainers removes the containers of gateway processes that are no longer running.
+// With all set, containers of running gateways are removed as well.
+func CleanupContainers(ctx context.Context, runtime containers.Runtime, all bool) (int, error) {
+	scope := transport.CleanupStale
+	if all {
+		scope = transport.CleanupAll
+	}
+	return transport.CleanupContainers(ctx, runtime, scope)
+}
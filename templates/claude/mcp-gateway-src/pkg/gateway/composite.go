@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// compositeServerName is the server name under which composite tools are registered
+const compositeServerName = "gateway"
+
+// metaToolPrefix starts the names of the tools implemented by the gateway itself
+const metaToolPrefix = compositeServerName + "-"
+
+// isMetaToolName reports whether a name belongs to a gateway meta-tool
+func isMetaToolName(name string) bool {
+	switch name {
+	case batchToolName, searchToolName, callToolName,
+		listServersToolName, serverConfigToolName, activateServerToolName, deactivateServerToolName:
+		return true
+	}
+	return false
+}
+
+// templatePattern matches {{$.path}} placeholders embedded in a string
+var templatePattern = regexp.MustCompile(`\{\{\s*(\$[^}]*?)\s*\}\}`)
+
+// CompositeTool is a gateway-level tool that chains calls to other tools
+type CompositeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema map[string]any  `json:"inputSchema,omitempty"` // Declared parameters, defaults to an empty object
+	Steps       []CompositeStep `json:"steps"`
+	Output      any             `json:"output,omitempty"` // Template of the result, defaults to the last step's result
+}
+
+// CompositeStep is a single tool call of a composite tool.
+// Argument values that are exactly "$.path" are replaced by the referenced value, strings containing
+// "{{$.path}}" get it interpolated. Paths start at "$.input" for the tool arguments and
+// "$.steps.<id>" for earlier results (with "text", "content" and "structuredContent").
+type CompositeStep struct {
+	ID        string         `json:"id"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// validateCompositeTools rejects composite definitions that can't be executed
+func (c GatewayConfig) validateCompositeTools() error {
+	names := make(map[string]bool, len(c.CompositeTools))
+	for _, tool := range c.CompositeTools {
+		if tool.Name == "" {
+			return fmt.Errorf("composite tool without a name")
+		}
+		if names[tool.Name] {
+			return fmt.Errorf("composite tool %q is defined twice", tool.Name)
+		}
+		if strings.HasPrefix(tool.Name, metaToolPrefix) {
+			return fmt.Errorf("composite tool %q: the %q prefix is reserved for gateway meta-tools", tool.Name, metaToolPrefix)
+		}
+		names[tool.Name] = true
+	}
+
+	for _, tool := range c.CompositeTools {
+		if len(tool.Steps) == 0 {
+			return fmt.Errorf("composite tool %q has no steps", tool.Name)
+		}
+		if tool.InputSchema != nil && schemaType(tool.InputSchema) != "object" {
+			return fmt.Errorf("composite tool %q: inputSchema must be of type object", tool.Name)
+		}
+
+		ids := make(map[string]bool, len(tool.Steps))
+		for i, step := range tool.Steps {
+			if step.ID == "" || step.Tool == "" {
+				return fmt.Errorf("composite tool %q: step %d needs an id and a tool", tool.Name, i)
+			}
+			if ids[step.ID] {
+				return fmt.Errorf("composite tool %q: step id %q is used twice", tool.Name, step.ID)
+			}
+			// Composites calling composites could recurse forever
+			if names[step.Tool] {
+				return fmt.Errorf("composite tool %q: step %q can't call the composite tool %q", tool.Name, step.ID, step.Tool)
+			}
+			ids[step.ID] = true
+		}
+	}
+
+	return nil
+}
+
+// registerCompositeTools replaces the composite tools with the ones of the current config
+func (g *Gateway) registerCompositeTools() {
+	if removed := g.tools.removeServer(compositeServerName); len(removed) > 0 && g.config.ToolMode != toolModeSearch {
+		g.server.RemoveTools(removed...)
+	}
+
+	for _, def := range g.config.CompositeTools {
+		schema := def.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+
+		g.addTool(compositeServerName, &mcp.Tool{
+			Name:        def.Name,
+			Description: def.Description,
+			InputSchema: schema,
+		}, g.compositeToolHandler(def))
+	}
+}
+
+// compositeToolHandler runs the steps of a composite tool through the regular tool handlers
+func (g *Gateway) compositeToolHandler(def CompositeTool) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := req.Params.Arguments
+		if registered, ok := g.tools.get(def.Name); ok && registered.input != nil && !g.config.Validation.DisableInput {
+			validated, err := validateArguments(registered.input, arguments, !g.config.Validation.DisableDefaults)
+			if err != nil {
+				return gatewayError(errCodeInvalidArgs,
+					fmt.Sprintf("Invalid arguments for %s: %v", def.Name, err),
+					map[string]any{"tool": def.Name}), nil
+			}
+			arguments = validated
+		}
+
+		input := map[string]any{}
+		if len(arguments) > 0 {
+			if err := json.Unmarshal(arguments, &input); err != nil {
+				return gatewayError(errCodeInvalidArgs, fmt.Sprintf("Invalid arguments for %s: %v", def.Name, err), map[string]any{"tool": def.Name}), nil
+			}
+		}
+
+		scope := map[string]any{"input": input, "steps": map[string]any{}}
+		var last *mcp.CallToolResult
+
+		// Later steps see the full results, only the output of the composite tool is limited
+		stepCtx := context.WithValue(ctx, unlimitedResultsKey, true)
+
+		for _, step := range def.Steps {
+			stepArgs, err := json.Marshal(renderTemplate(step.Arguments, scope))
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode arguments of step %q: %w", step.ID, err)
+			}
+
+			result, err := g.callRegisteredTool(stepCtx, req, step.Tool, stepArgs)
+			if err == nil && result.IsError {
+				err = fmt.Errorf("%s", resultText(result))
+			}
+			if err != nil {
+				zap.L().Warn("Composite tool step failed",
+					zap.String("component", "TOOLS"),
+					zap.String("tool", def.Name),
+					zap.String("step", step.ID),
+					zap.Error(err))
+				return gatewayError(errCodeStepFailed,
+					fmt.Sprintf("Step %q of %s (%s) failed: %v", step.ID, def.Name, step.Tool, err),
+					map[string]any{"tool": def.Name, "step": step.ID, "stepTool": step.Tool}), nil
+			}
+
+			scope["steps"].(map[string]any)[step.ID] = stepScope(result)
+			last = result
+		}
+
+		if def.Output == nil {
+			return g.limitResult(ctx, req.Session, "", def.Name, last), nil
+		}
+
+		output := renderTemplate(def.Output, scope)
+		if text, ok := output.(string); ok {
+			return g.limitResult(ctx, req.Session, "", def.Name, &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}), nil
+		}
+
+		text, err := json.Marshal(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output of %s: %w", def.Name, err)
+		}
+		result := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: string(text)}}}
+		if _, ok := output.(map[string]any); ok {
+			result.StructuredContent = output
+		}
+		return g.limitResult(ctx, req.Session, "", def.Name, result), nil
+	}
+}
+
+// stepScope exposes a step result to the templates of later steps
+func stepScope(result *mcp.CallToolResult) map[string]any {
+	scope := map[string]any{"text": resultText(result)}
+
+	// Work on the JSON form so paths see plain maps and slices
+	if data, err := json.Marshal(result); err == nil {
+		var generic map[string]any
+		if json.Unmarshal(data, &generic) == nil {
+			scope["content"] = generic["content"]
+			scope["structuredContent"] = generic["structuredContent"]
+		}
+	}
+	return scope
+}
+
+// resultText concatenates the text content of a result
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// renderTemplate resolves the path references in a template value
+func renderTemplate(template any, scope map[string]any) any {
+	switch t := template.(type) {
+	case string:
+		trimmed := strings.TrimSpace(t)
+		if strings.HasPrefix(trimmed, "$.") && !strings.Contains(trimmed, " ") {
+			value, _ := lookupPath(scope, trimmed)
+			return value
+		}
+		return templatePattern.ReplaceAllStringFunc(t, func(match string) string {
+			value, ok := lookupPath(scope, templatePattern.FindStringSubmatch(match)[1])
+			if !ok || value == nil {
+				return ""
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		})
+	case map[string]any:
+		rendered := make(map[string]any, len(t))
+		for k, v := range t {
+			rendered[k] = renderTemplate(v, scope)
+		}
+		return rendered
+	case []any:
+		rendered := make([]any, len(t))
+		for i, v := range t {
+			rendered[i] = renderTemplate(v, scope)
+		}
+		return rendered
+	default:
+		return template
+	}
+}
+
+// lookupPath resolves a JSONPath-style reference such as "$.steps.fetch.content[0].text"
+func lookupPath(scope map[string]any, path string) (any, bool) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, false
+	}
+
+	var current any = scope
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			m, ok := current.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			current, ok = m[rest[:end]]
+			if !ok {
+				return nil, false
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			list, ok := current.([]any)
+			if err != nil || !ok || index < 0 || index >= len(list) {
+				return nil, false
+			}
+			current = list[index]
+			rest = rest[end+1:]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestValidateCompositeToolsRejectsReservedNames(t *testing.T) {
+	for _, name := range []string{callToolName, "gateway-lookup"} {
+		config := GatewayConfig{CompositeTools: []CompositeTool{{
+			Name:  name,
+			Steps: []CompositeStep{{ID: "a", Tool: "brave-search"}},
+		}}}
+		if err := config.validateCompositeTools(); err == nil {
+			t.Errorf("composite tool %q was accepted", name)
+		}
+	}
+}
+
+func TestToolRegistryKeepsNameOfFirstServer(t *testing.T) {
+	registry := newToolRegistry()
+	if _, added := registry.add(&registeredTool{serverName: "brave", tool: &mcp.Tool{Name: "brave-search"}}); !added {
+		t.Fatal("first tool was not added")
+	}
+	if owner, added := registry.add(&registeredTool{serverName: compositeServerName, tool: &mcp.Tool{Name: "brave-search"}}); added || owner != "brave" {
+		t.Errorf("colliding tool: added %v, owner %q", added, owner)
+	}
+	if _, added := registry.add(&registeredTool{serverName: "brave", tool: &mcp.Tool{Name: "brave-search"}}); !added {
+		t.Error("tool of the same server was not replaced")
+	}
+}
+
+func TestCompositeStepsSeeUnlimitedResults(t *testing.T) {
+	g := newLimitedGateway(overflowTruncate)
+	g.tools = newToolRegistry()
+	tool := func(name string, handler func(arguments map[string]any) string) {
+		g.tools.add(&registeredTool{
+			serverName: "fetch",
+			tool:       &mcp.Tool{Name: name},
+			handler: func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				var arguments map[string]any
+				json.Unmarshal(req.Params.Arguments, &arguments)
+				text := handler(arguments)
+				return g.limitResult(ctx, req.Session, "fetch", name, &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}), nil
+			},
+		})
+	}
+	tool("fetch-page", func(map[string]any) string { return strings.Repeat("x", 500) })
+	tool("fetch-length", func(arguments map[string]any) string { return strconv.Itoa(len(arguments["text"].(string))) })
+
+	run := func(def CompositeTool) string {
+		t.Helper()
+		result, err := g.compositeToolHandler(def)(t.Context(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: def.Name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.IsError {
+			t.Fatalf("composite tool failed: %s", resultText(result))
+		}
+		return resultText(result)
+	}
+
+	// The second step gets the whole page although it is above the limit of the tool
+	length := run(CompositeTool{Name: "page-length", Steps: []CompositeStep{
+		{ID: "page", Tool: "fetch-page"},
+		{ID: "length", Tool: "fetch-length", Arguments: map[string]any{"text": "$.steps.page.text"}},
+	}})
+	if length != "500" {
+		t.Errorf("second step saw %s bytes, want the 500 bytes of the first", length)
+	}
+
+	// The composite result itself is limited, whether it is the last step's or rendered from the output template
+	for _, def := range []CompositeTool{
+		{Name: "page", Steps: []CompositeStep{{ID: "page", Tool: "fetch-page"}}},
+		{Name: "page-twice", Steps: []CompositeStep{{ID: "page", Tool: "fetch-page"}}, Output: "{{$.steps.page.text}}{{$.steps.page.text}}"},
+	} {
+		if text := run(def); len(text) > 200 {
+			t.Errorf("%s returned %d bytes above the limit", def.Name, len(text))
+		}
+	}
+}
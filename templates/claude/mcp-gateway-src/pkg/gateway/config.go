@@ -0,0 +1,501 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
+)
+
+// Tool modes
+const (
+	toolModeAll    = "all"    // Every backend tool is listed
+	toolModeSearch = "search" // Only the search meta-tools are listed
+)
+
+// gatewayConfigKey is the reserved top-level key of the user config that holds gateway settings
+// rather than the configuration of an MCP server
+const gatewayConfigKey = "gateway"
+
+// GatewayConfig holds gateway-level settings that are not tied to a single catalog server
+type GatewayConfig struct {
+	Liveness    LivenessConfig         `json:"liveness"`
+	Retry       map[string]RetryPolicy `json:"retry"` // Session creation retries keyed by transport ("docker", "remote", "github")
+	Rediscovery RediscoveryConfig      `json:"rediscovery"`
+	Discovery   DiscoveryConfig        `json:"discovery"`
+	Resources   transport.Resources    `json:"resources"` // Container limits on top of the catalog-derived defaults
+	Egress      EgressConfig           `json:"egress"`
+	Secrets     SecretsConfig          `json:"secrets"`
+	Images      transport.Images       `json:"images"` // Pull policy, registry mirrors and credentials
+	PrePull     PrePullConfig          `json:"prePull"`
+	Breaker     CircuitBreakerConfig   `json:"circuitBreaker"`
+	Concurrency ConcurrencyConfig      `json:"concurrency"`
+	RateLimits  RateLimitConfig        `json:"rateLimits"`
+	Cache       CacheConfig            `json:"cache"`
+	Results     ResultLimitConfig      `json:"resultLimits"`
+	Validation  ValidationConfig       `json:"validation"`
+	MetaTools   MetaToolsConfig        `json:"metaTools"`
+	ToolMode    string                 `json:"toolMode,omitempty"` // "all" (default) or "search"
+
+	// CompositeTools are gateway-level tools chaining calls to other tools
+	CompositeTools []CompositeTool `json:"compositeTools,omitempty"`
+
+	// Servers holds per-server overrides keyed by config key or catalog server name
+	Servers map[string]ServerSettings `json:"servers,omitempty"`
+}
+
+// ServerSettings overrides gateway settings for a single server, unset fields fall back to the global value
+type ServerSettings struct {
+	Concurrency *ConcurrencyConfig   `json:"concurrency,omitempty"`
+	RateLimit   *RateLimit           `json:"rateLimit,omitempty"`
+	Cache       *ServerCacheConfig   `json:"cache,omitempty"`
+	ResultLimit *ResultLimit         `json:"resultLimit,omitempty"`
+	Lazy        *bool                `json:"lazy,omitempty"`
+	Resources   *transport.Resources `json:"resources,omitempty"`
+	Secrets     *SecretsConfig       `json:"secrets,omitempty"`
+	PullPolicy  string               `json:"pullPolicy,omitempty"`
+}
+
+// SecretsConfig controls how secrets reach Docker containers
+type SecretsConfig struct {
+	Delivery string `json:"delivery,omitempty"` // "env" (default) or "files", which only servers reading <NAME>_FILE support
+}
+
+// EgressConfig controls how the catalog's allowHosts are enforced for Docker servers
+type EgressConfig struct {
+	Disabled   bool   `json:"disabled,omitempty"`   // Give servers with allowHosts unrestricted egress
+	Network    string `json:"network,omitempty"`    // Prefix of the internal networks, one per server, defaults to mcp-gateway-egress
+	ListenHost string `json:"listenHost,omitempty"` // Address of the proxies, defaults to the gateway IP of each server's network
+}
+
+// DiscoveryConfig controls how the tools of configured servers are discovered
+type DiscoveryConfig struct {
+	Lazy  bool                 `json:"lazy,omitempty"` // Register catalog-declared tools and start servers on their first call
+	Cache DiscoveryCacheConfig `json:"cache"`
+}
+
+// DiscoveryCacheConfig controls the on-disk cache of discovered tool lists
+type DiscoveryCacheConfig struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Dir      string `json:"dir,omitempty"` // Defaults to the user cache directory
+}
+
+// LivenessConfig controls how long-lived backend sessions are health-checked and restarted
+type LivenessConfig struct {
+	Disabled         bool     `json:"disabled,omitempty"`         // Turn off pings and automatic restarts
+	Interval         Duration `json:"interval,omitempty"`         // Time between two MCP pings
+	Timeout          Duration `json:"timeout,omitempty"`          // Deadline for a single ping
+	FailureThreshold int      `json:"failureThreshold,omitempty"` // Consecutive failed pings before a restart
+	MaxRestarts      int      `json:"maxRestarts,omitempty"`      // Restart attempts per crash, 0 means unlimited
+	Backoff          Backoff  `json:"backoff"`                    // Delay between restart attempts
+}
+
+// RetryPolicy controls how often creating a backend session is retried before giving up
+type RetryPolicy struct {
+	MaxAttempts int     `json:"maxAttempts,omitempty"` // Total attempts including the first one
+	Backoff     Backoff `json:"backoff"`
+}
+
+// RediscoveryConfig controls the background loop that retries servers whose tools failed to load
+type RediscoveryConfig struct {
+	Disabled bool    `json:"disabled,omitempty"`
+	Backoff  Backoff `json:"backoff"` // Delay between discovery attempts of one failed server
+}
+
+// CircuitBreakerConfig controls when tool calls to a failing server are rejected without contacting it
+type CircuitBreakerConfig struct {
+	Disabled         bool     `json:"disabled,omitempty"`
+	FailureThreshold int      `json:"failureThreshold,omitempty"` // Consecutive failed calls that open the breaker
+	Cooldown         Duration `json:"cooldown,omitempty"`         // Time the breaker stays open before a trial call
+}
+
+// ConcurrencyConfig limits parallel tool calls to one server and queues the calls above the limit
+type ConcurrencyConfig struct {
+	MaxConcurrent int      `json:"maxConcurrent,omitempty"` // Parallel calls allowed, 0 means unlimited
+	QueueSize     int      `json:"queueSize,omitempty"`     // Calls allowed to wait for a slot, further calls are rejected
+	QueueTimeout  Duration `json:"queueTimeout,omitempty"`  // Maximum time a call waits in the queue
+}
+
+// merge returns c with every field that is set in override replaced
+func (c ConcurrencyConfig) merge(override ConcurrencyConfig) ConcurrencyConfig {
+	if override.MaxConcurrent != 0 {
+		c.MaxConcurrent = override.MaxConcurrent
+	}
+	if override.QueueSize != 0 {
+		c.QueueSize = override.QueueSize
+	}
+	if override.QueueTimeout != 0 {
+		c.QueueTimeout = override.QueueTimeout
+	}
+	return c
+}
+
+// RateLimitConfig holds token-bucket limits per scope, a nil limit disables that scope
+type RateLimitConfig struct {
+	PerToken   *RateLimit           `json:"perToken,omitempty"`   // Shared by all requests with the same auth token
+	PerSession *RateLimit           `json:"perSession,omitempty"` // Per inbound MCP session
+	PerServer  *RateLimit           `json:"perServer,omitempty"`  // Per backend server, overridable in servers.<name>.rateLimit
+	PerTool    *RateLimit           `json:"perTool,omitempty"`    // Per tool, overridable in tools
+	Tools      map[string]RateLimit `json:"tools,omitempty"`      // Per-tool overrides keyed by exposed tool name
+}
+
+// RateLimit allows Requests calls per Per interval with bursts of up to Burst calls
+type RateLimit struct {
+	Requests float64  `json:"requests"`
+	Per      Duration `json:"per,omitempty"`   // Defaults to one minute
+	Burst    int      `json:"burst,omitempty"` // Defaults to Requests
+}
+
+// interval returns the period Requests refers to
+func (r RateLimit) interval() time.Duration {
+	if r.Per <= 0 {
+		return time.Minute
+	}
+	return time.Duration(r.Per)
+}
+
+// perSecond returns the refill rate of the limit
+func (r RateLimit) perSecond() float64 {
+	return max(r.Requests, 1e-9) / r.interval().Seconds()
+}
+
+// burst returns the bucket capacity of the limit
+func (r RateLimit) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return max(int(r.Requests), 1)
+}
+
+// CacheConfig controls caching of results of tools annotated as read-only or idempotent
+type CacheConfig struct {
+	Enabled      bool     `json:"enabled,omitempty"`
+	TTL          Duration `json:"ttl,omitempty"`          // Default time to live, overridable per server
+	MaxEntries   int      `json:"maxEntries,omitempty"`   // In-memory entry limit
+	MaxBytes     int64    `json:"maxBytes,omitempty"`     // In-memory size limit
+	Dir          string   `json:"dir,omitempty"`          // Optional directory that keeps entries across restarts
+	MaxDiskBytes int64    `json:"maxDiskBytes,omitempty"` // Size limit of Dir
+}
+
+// ServerCacheConfig overrides caching for a single server
+type ServerCacheConfig struct {
+	Disabled bool     `json:"disabled,omitempty"`
+	TTL      Duration `json:"ttl,omitempty"`
+}
+
+// ResultLimitConfig limits the size of tool result content returned to the client
+type ResultLimitConfig struct {
+	ResultLimit                             // Default for all tools
+	Tools            map[string]ResultLimit `json:"tools,omitempty"`            // Overrides keyed by exposed tool name
+	ResourceTTL      Duration               `json:"resourceTTL,omitempty"`      // Lifetime of results spilled to resources
+	MaxResourceBytes int                    `json:"maxResourceBytes,omitempty"` // Total size of results spilled to resources
+}
+
+// ResultLimit caps the content and structured content of a single tool result
+type ResultLimit struct {
+	MaxBytes  int    `json:"maxBytes,omitempty"`
+	MaxTokens int    `json:"maxTokens,omitempty"` // Estimated as four bytes per token
+	Strategy  string `json:"strategy,omitempty"`  // "truncate" (default) or "resource"
+}
+
+// ValidationConfig controls checking of tool calls against the schemas captured during discovery
+type ValidationConfig struct {
+	DisableInput    bool `json:"disableInput,omitempty"`    // Forward arguments without checking them against inputSchema
+	DisableDefaults bool `json:"disableDefaults,omitempty"` // Don't fill in schema defaults for missing arguments
+	Output          bool `json:"output,omitempty"`          // Check structuredContent against the tool's outputSchema
+}
+
+// MetaToolsConfig controls the tools implemented by the gateway itself
+type MetaToolsConfig struct {
+	Batch      BatchConfig      `json:"batch"`
+	Search     SearchConfig     `json:"search"`
+	Activation ActivationConfig `json:"activation"`
+}
+
+// ActivationConfig controls the tools that let the model activate catalog servers at runtime.
+// They are off unless enabled, since any connected client could use them to start catalog containers.
+type ActivationConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// SearchConfig controls the tools exposed in search mode
+type SearchConfig struct {
+	DefaultLimit int `json:"defaultLimit,omitempty"` // Results returned when the query sets no limit
+}
+
+// BatchConfig controls the gateway-batch tool
+type BatchConfig struct {
+	Disabled    bool `json:"disabled,omitempty"`
+	MaxParallel int  `json:"maxParallel,omitempty"` // Entries running at once
+	MaxCalls    int  `json:"maxCalls,omitempty"`    // Entries accepted in one batch
+}
+
+// DefaultGatewayConfig returns the settings used when the user config has no gateway section
+func DefaultGatewayConfig() GatewayConfig {
+	return GatewayConfig{
+		Liveness: LivenessConfig{
+			Interval:         Duration(30 * time.Second),
+			Timeout:          Duration(5 * time.Second),
+			FailureThreshold: 2,
+			Backoff: Backoff{
+				Initial:    Duration(time.Second),
+				Max:        Duration(time.Minute),
+				Multiplier: 2,
+			},
+		},
+		Retry: map[string]RetryPolicy{
+			transport.DockerName: {
+				MaxAttempts: 3,
+				Backoff:     Backoff{Initial: Duration(time.Second), Max: Duration(10 * time.Second), Multiplier: 2, Jitter: 0.2},
+			},
+			transport.RemoteName: {
+				MaxAttempts: 4,
+				Backoff:     Backoff{Initial: Duration(500 * time.Millisecond), Max: Duration(10 * time.Second), Multiplier: 2, Jitter: 0.2},
+			},
+			transport.GitHubName: {
+				MaxAttempts: 2,
+				Backoff:     Backoff{Initial: Duration(2 * time.Second), Max: Duration(10 * time.Second), Multiplier: 2, Jitter: 0.2},
+			},
+		},
+		Rediscovery: RediscoveryConfig{
+			Backoff: Backoff{Initial: Duration(15 * time.Second), Max: Duration(5 * time.Minute), Multiplier: 2, Jitter: 0.2},
+		},
+		PrePull: PrePullConfig{
+			Parallelism: defaultPullParallelism,
+		},
+		Breaker: CircuitBreakerConfig{
+			FailureThreshold: 5,
+			Cooldown:         Duration(30 * time.Second),
+		},
+		Concurrency: ConcurrencyConfig{
+			QueueSize:    100,
+			QueueTimeout: Duration(30 * time.Second),
+		},
+		Cache: CacheConfig{
+			TTL:          Duration(5 * time.Minute),
+			MaxEntries:   1000,
+			MaxBytes:     64 << 20,
+			MaxDiskBytes: 256 << 20,
+		},
+		Results: ResultLimitConfig{
+			ResourceTTL:      Duration(time.Hour),
+			MaxResourceBytes: 64 << 20,
+		},
+		MetaTools: MetaToolsConfig{
+			Batch:  BatchConfig{MaxParallel: 4, MaxCalls: 20},
+			Search: SearchConfig{DefaultLimit: 10},
+		},
+		ToolMode: toolModeAll,
+	}
+}
+
+// RetryPolicy returns the retry policy for the transport used by the given server type
+func (c GatewayConfig) RetryPolicy(serverType string) RetryPolicy {
+	name := transport.Name(serverType)
+	if policy, ok := c.Retry[name]; ok {
+		return policy
+	}
+	return DefaultGatewayConfig().Retry[name]
+}
+
+// ConcurrencyFor returns the concurrency settings of a server
+func (c GatewayConfig) ConcurrencyFor(serverName string) ConcurrencyConfig {
+	if settings, ok := c.Servers[serverName]; ok && settings.Concurrency != nil {
+		return c.Concurrency.merge(*settings.Concurrency)
+	}
+	return c.Concurrency
+}
+
+// RateLimitFor returns the rate limit of a server, or nil if it is not limited
+func (c GatewayConfig) RateLimitFor(serverName string) *RateLimit {
+	if settings, ok := c.Servers[serverName]; ok && settings.RateLimit != nil {
+		return settings.RateLimit
+	}
+	return c.RateLimits.PerServer
+}
+
+// CacheTTLFor returns how long results of a server are cached, or false if they are not cached
+func (c GatewayConfig) CacheTTLFor(serverName string) (time.Duration, bool) {
+	if !c.Cache.Enabled {
+		return 0, false
+	}
+
+	ttl := time.Duration(c.Cache.TTL)
+	if settings, ok := c.Servers[serverName]; ok && settings.Cache != nil {
+		if settings.Cache.Disabled {
+			return 0, false
+		}
+		if settings.Cache.TTL > 0 {
+			ttl = time.Duration(settings.Cache.TTL)
+		}
+	}
+	return ttl, ttl > 0
+}
+
+// ResourcesFor returns the container limits of a server: defaults by catalog server name, then global, then per-server settings
+func (c GatewayConfig) ResourcesFor(serverName string) transport.Resources {
+	resources := transport.DefaultResources(serverName).Merge(c.Resources)
+	if settings, ok := c.Servers[serverName]; ok && settings.Resources != nil {
+		resources = resources.Merge(*settings.Resources)
+	}
+	return resources
+}
+
+// SecretDeliveryFor returns the secret delivery mode of a server
+func (c GatewayConfig) SecretDeliveryFor(serverName string) string {
+	if settings, ok := c.Servers[serverName]; ok && settings.Secrets != nil && settings.Secrets.Delivery != "" {
+		return settings.Secrets.Delivery
+	}
+	return c.Secrets.Delivery
+}
+
+// validateSecrets rejects unknown secret delivery modes and file delivery to every server
+func (c GatewayConfig) validateSecrets() error {
+	if !transport.ValidSecretDelivery(c.Secrets.Delivery) {
+		return fmt.Errorf("unknown secret delivery %q", c.Secrets.Delivery)
+	}
+	// Servers that don't read <NAME>_FILE would silently lose their credentials, so files
+	// are only delivered to servers whose settings ask for them
+	if c.Secrets.Delivery == transport.SecretsFiles {
+		return fmt.Errorf("secret delivery %q can only be set per server, for servers that read <NAME>_FILE", transport.SecretsFiles)
+	}
+	for name, settings := range c.Servers {
+		if settings.Secrets != nil && !transport.ValidSecretDelivery(settings.Secrets.Delivery) {
+			return fmt.Errorf("unknown secret delivery %q for %q", settings.Secrets.Delivery, name)
+		}
+	}
+	return nil
+}
+
+// ImagesFor returns how the image of a server is obtained
+func (c GatewayConfig) ImagesFor(serverName string) transport.Images {
+	images := c.Images
+	if settings, ok := c.Servers[serverName]; ok && settings.PullPolicy != "" {
+		images.PullPolicy = settings.PullPolicy
+	}
+	return images
+}
+
+// validateImages rejects unknown pull policies and empty mirrors
+func (c GatewayConfig) validateImages() error {
+	if err := c.Images.Validate(); err != nil {
+		return err
+	}
+	for name, settings := range c.Servers {
+		if !transport.ValidPullPolicy(settings.PullPolicy) {
+			return fmt.Errorf("unknown pull policy %q for %q", settings.PullPolicy, name)
+		}
+	}
+	return nil
+}
+
+// validateResources rejects container limits docker would refuse
+func (c GatewayConfig) validateResources() error {
+	if err := c.Resources.Validate(); err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	for name, settings := range c.Servers {
+		if settings.Resources == nil {
+			continue
+		}
+		if err := settings.Resources.Validate(); err != nil {
+			return fmt.Errorf("resources of %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// LazyFor reports whether a server should only be started on its first tool call
+func (c GatewayConfig) LazyFor(serverName string) bool {
+	if settings, ok := c.Servers[serverName]; ok && settings.Lazy != nil {
+		return *settings.Lazy
+	}
+	return c.Discovery.Lazy
+}
+
+// ResultLimitFor returns the result limit of a tool, preferring tool over server over global settings
+func (c GatewayConfig) ResultLimitFor(serverName string, toolName string) ResultLimit {
+	if limit, ok := c.Results.Tools[toolName]; ok {
+		return limit
+	}
+	if settings, ok := c.Servers[serverName]; ok && settings.ResultLimit != nil {
+		return *settings.ResultLimit
+	}
+	return c.Results.ResultLimit
+}
+
+// resolveServerKeys re-keys per-server settings from config keys (e.g. "brave") to catalog server names
+func (c *GatewayConfig) resolveServerKeys(instructionMap InstructionMap) {
+	if len(c.Servers) == 0 {
+		return
+	}
+
+	resolved := make(map[string]ServerSettings, len(c.Servers))
+	for key, settings := range c.Servers {
+		if serverName, ok := GetServerNameFromInstructions(instructionMap, key); ok {
+			key = serverName
+		}
+		resolved[key] = settings
+	}
+	c.Servers = resolved
+}
+
+// parseConfig splits the raw config JSON into the gateway settings and the per-server user configs
+func parseConfig(configJSON []byte) (GatewayConfig, map[string]UserConfig, error) {
+	gatewayConfig := DefaultGatewayConfig()
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(configJSON, &raw); err != nil {
+		return gatewayConfig, nil, err
+	}
+
+	if gatewayJSON, ok := raw[gatewayConfigKey]; ok {
+		if err := json.Unmarshal(gatewayJSON, &gatewayConfig); err != nil {
+			return gatewayConfig, nil, fmt.Errorf("invalid %q section: %w", gatewayConfigKey, err)
+		}
+		delete(raw, gatewayConfigKey)
+	}
+
+	userConfigs := make(map[string]UserConfig, len(raw))
+	for key, value := range raw {
+		var userConfig UserConfig
+		if err := json.Unmarshal(value, &userConfig); err != nil {
+			return gatewayConfig, nil, fmt.Errorf("invalid config for %q: %w", key, err)
+		}
+		userConfigs[key] = userConfig
+	}
+
+	return gatewayConfig, userConfigs, nil
+}
+
+// Duration is a time.Duration that is written in JSON as a Go duration string (e.g. "30s")
+// or as a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string or a number of seconds: %s", data)
+	}
+
+	parsed, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// discoveryEntry is a persisted tool list of a server
+type discoveryEntry struct {
+	Identity     string      `json:"identity"` // Image reference or remote URL, for humans inspecting the cache
+	DiscoveredAt time.Time   `json:"discoveredAt"`
+	Tools        []*mcp.Tool `json:"tools"` // Tools as listed by the server, without the gateway prefix
+}
+
+// discoveryCache persists ListTools results on disk so startup doesn't have to wait for backends
+type discoveryCache struct {
+	dir string // Empty when the cache is disabled
+}
+
+// defaultDiscoveryCacheDir returns the directory used when the config sets none
+func defaultDiscoveryCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "mcp-gateway", "tools")
+}
+
+// newDiscoveryCache creates the discovery cache described by the config
+func newDiscoveryCache(cfg DiscoveryCacheConfig) *discoveryCache {
+	if cfg.Disabled {
+		return &discoveryCache{}
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		dir = defaultDiscoveryCacheDir()
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		zap.L().Warn("Disabling tool discovery cache", zap.String("component", "TOOLS"), zap.String("dir", dir), zap.Error(err))
+		return &discoveryCache{}
+	}
+	return &discoveryCache{dir: dir}
+}
+
+// serverIdentity returns what makes a server's tool list immutable: its image (pinned by digest
+// in the catalog) or its remote URL. GitHub servers are built from source and have none.
+func serverIdentity(server catalog.Server) string {
+	switch {
+	case server.Remote.URL != "":
+		return server.Remote.URL
+	case server.Type == "github":
+		return ""
+	default:
+		return server.Image
+	}
+}
+
+// discoveryKey derives the cache key of a server from its identity and a hash of its merged config
+func discoveryKey(server catalog.Server) string {
+	identity := serverIdentity(server)
+	if identity == "" {
+		return ""
+	}
+
+	// The merged server holds the user config, including secrets, so it only ever enters the key hashed
+	config, err := json.Marshal(server)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(identity))
+	h.Write([]byte{0})
+	h.Write(config)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load returns the cached tools of a server
+func (c *discoveryCache) load(server catalog.Server) ([]*mcp.Tool, bool) {
+	key := discoveryKey(server)
+	if c.dir == "" || key == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry discoveryEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Tools) == 0 {
+		return nil, false
+	}
+	return entry.Tools, true
+}
+
+// store persists the tools a server listed
+func (c *discoveryCache) store(server catalog.Server, tools []*mcp.Tool) {
+	key := discoveryKey(server)
+	if c.dir == "" || key == "" || len(tools) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(discoveryEntry{
+		Identity:     serverIdentity(server),
+		DiscoveredAt: time.Now(),
+		Tools:        tools,
+	})
+	if err != nil {
+		return
+	}
+
+	// Write atomically so concurrent gateways never read a partial entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil || os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json")) != nil {
+		os.Remove(tmp.Name())
+	}
+}
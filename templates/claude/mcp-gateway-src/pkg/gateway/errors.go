@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// gatewayMetaKey is the _meta key under which the gateway attaches machine-readable details
+const gatewayMetaKey = "e2b.dev/gateway"
+
+// Error codes reported by the gateway itself rather than by a backend server
+const (
+	errCodeCircuitOpen  = "circuit_open"
+	errCodeQueueFull    = "queue_full"
+	errCodeQueueTimeout = "queue_timeout"
+	errCodeRateLimited  = "rate_limited"
+	errCodeInvalidArgs  = "invalid_arguments"
+	errCodeInvalidOut   = "invalid_output"
+	errCodeUnknownTool  = "unknown_tool"
+
+	errCodeUnknownServer    = "unknown_server"
+	errCodeActivationFailed = "activation_failed"
+	errCodeStepFailed       = "step_failed"
+)
+
+// gatewayError builds a tool error result for failures produced by the gateway.
+// The message is readable by the model, the details are attached under _meta for programmatic clients.
+func gatewayError(code string, message string, details map[string]any) *mcp.CallToolResult {
+	meta := map[string]any{"code": code}
+	for k, v := range details {
+		meta[k] = v
+	}
+
+	return &mcp.CallToolResult{
+		Meta:    mcp.Meta{gatewayMetaKey: meta},
+		Content: []mcp.Content{&mcp.TextContent{Text: message}},
+		IsError: true,
+	}
+}
@@ -2,10 +2,11 @@ package gateway
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
+	"e2b.dev/mcp-gateway/pkg/containers"
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -16,15 +17,39 @@ type UserConfig map[string]any
 // Gateway holds the application state that can be hot-reloaded
 type Gateway struct {
 	catalog        catalog.Catalog
+	pristine       map[string]catalog.Server // Catalog servers before any user config was merged
 	server         *mcp.Server
 	pool           *ClientPool
+	tools          *toolRegistry
+	searchIndex    *searchIndex
+	breakers       *breakerRegistry
+	limiters       *limiterRegistry
+	rateLimiter    *rateLimiter
+	cache          *resultCache
+	spill          *spillStore
+	discoveryCache *discoveryCache
+	prePull        *prePuller // Images pulled since the config was loaded, nil when disabled
 	instructionMap InstructionMap
 	userConfigs    map[string]UserConfig
+	config         GatewayConfig
 	toolsLoading   sync.Mutex // Held while dynamicallyListTools is running
+
+	// Guards replacing catalog.Servers and userConfigs, which are swapped rather than modified so that
+	// readers can take a snapshot without waiting for a discovery holding toolsLoading
+	catalogMu sync.RWMutex
+
+	activating map[string]bool // Servers being started by the activation tool, guarded by toolsLoading
+
+	lazyMu      sync.Mutex
+	lazyServers map[string]*lazyServer // Servers registered from catalog stubs, keyed by server name
+
+	discoveryMu       sync.Mutex
+	failedDiscoveries map[string]*failedDiscovery // Servers whose tools could not be listed yet
+	rediscovering     bool                        // Whether the rediscovery loop is running
 }
 
-// New creates a new Gateway instance
-func New(ctx context.Context, catalogURLs []string, mappingPath string) (*Gateway, error) {
+// New creates a new Gateway instance running Docker servers on the given container runtime
+func New(ctx context.Context, catalogURLs []string, mappingPath string, runtime containers.Runtime) (*Gateway, error) {
 	instructionMap, err := LoadInstructionMap(mappingPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load instruction map: %w", err)
@@ -36,29 +61,44 @@ func New(ctx context.Context, catalogURLs []string, mappingPath string) (*Gatewa
 	}
 
 	g := &Gateway{
-		pool:           NewClientPool(),
+		pool:           NewClientPool(runtime),
+		tools:          newToolRegistry(),
+		searchIndex:    &searchIndex{},
+		breakers:       newBreakerRegistry(DefaultGatewayConfig().Breaker),
+		limiters:       newLimiterRegistry(DefaultGatewayConfig()),
+		rateLimiter:    newRateLimiter(DefaultGatewayConfig()),
+		cache:          newResultCache(DefaultGatewayConfig().Cache),
+		spill:          newSpillStore(time.Duration(DefaultGatewayConfig().Results.ResourceTTL), DefaultGatewayConfig().Results.MaxResourceBytes),
+		discoveryCache: newDiscoveryCache(DefaultGatewayConfig().Discovery.Cache),
 		instructionMap: instructionMap,
 		catalog:        cat,
+		pristine:       cloneServers(cat.Servers),
+		config:         DefaultGatewayConfig(),
+
+		failedDiscoveries: make(map[string]*failedDiscovery),
+		lazyServers:       make(map[string]*lazyServer),
+		activating:        make(map[string]bool),
 	}
 
 	g.server = g.setupMCPServer()
+	g.server.AddResourceTemplate(spillTemplate(), func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return g.spill.read(ctx, req)
+	})
+	g.registerMetaTools()
 
 	return g, nil
 }
 
 // setupMCPServer creates and configures the MCP server with middleware
 func (g *Gateway) setupMCPServer() *mcp.Server {
-	server := mcp.NewServer(&mcp.Implementation{Name: "e2b-mcp-gateway", Version: "v0.0.1"}, &mcp.ServerOptions{HasTools: true})
+	server := mcp.NewServer(&mcp.Implementation{Name: "e2b-mcp-gateway", Version: "v0.0.1"}, &mcp.ServerOptions{HasTools: true, HasResources: true})
 
 	// Add session middleware and tools/list middleware
 	server.AddReceivingMiddleware(sessionMiddleware, func(next mcp.MethodHandler) mcp.MethodHandler {
 		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
 			if method == "tools/list" {
 				// Wait for tools to finish loading before proceeding
-				func() {
-					g.toolsLoading.Lock()
-					defer g.toolsLoading.Unlock()
-				}()
+				g.waitForTools()
 			}
 			return next(ctx, method, req)
 		}
@@ -67,6 +107,54 @@ func (g *Gateway) setupMCPServer() *mcp.Server {
 	return server
 }
 
+// catalogState returns the catalog servers and the user configs of the active servers.
+// Both are replaced on every change, so callers may read them without further locking.
+func (g *Gateway) catalogState() (map[string]catalog.Server, map[string]UserConfig) {
+	g.catalogMu.RLock()
+	defer g.catalogMu.RUnlock()
+	return g.catalog.Servers, g.userConfigs
+}
+
+// setCatalogState replaces the catalog servers and the user configs
+func (g *Gateway) setCatalogState(servers map[string]catalog.Server, userConfigs map[string]UserConfig) {
+	g.catalogMu.Lock()
+	defer g.catalogMu.Unlock()
+	g.catalog.Servers = servers
+	g.userConfigs = userConfigs
+}
+
+// waitForTools blocks until a running tool discovery has finished
+func (g *Gateway) waitForTools() {
+	g.toolsLoading.Lock()
+	defer g.toolsLoading.Unlock()
+}
+
+// registerMetaTools adds or removes the gateway-native tools according to the current config
+func (g *Gateway) registerMetaTools() {
+	if g.config.MetaTools.Batch.Disabled {
+		g.server.RemoveTools(batchToolName)
+	} else {
+		g.server.AddTool(batchTool(g.config.MetaTools.Batch), g.handleBatch)
+	}
+
+	if g.config.ToolMode == toolModeSearch {
+		search, call := searchTools(g.config.MetaTools.Search)
+		g.server.AddTool(search, g.handleSearchTools)
+		g.server.AddTool(call, g.handleCallTool)
+	} else {
+		g.server.RemoveTools(searchToolName, callToolName)
+	}
+
+	handlers := g.activationHandlers()
+	for _, tool := range activationTools() {
+		if !g.config.MetaTools.Activation.Enabled {
+			g.server.RemoveTools(tool.Name)
+		} else {
+			g.server.AddTool(tool, handlers[tool.Name])
+		}
+	}
+}
+
 // Server returns the MCP server instance
 func (g *Gateway) Server() *mcp.Server {
 	return g.server
@@ -79,15 +167,55 @@ func (g *Gateway) Pool() *ClientPool {
 
 // LoadConfig loads the configuration from JSON bytes and updates the application state
 func (g *Gateway) LoadConfig(ctx context.Context, configJSON []byte) error {
-	var userConfigs map[string]UserConfig
-	if err := json.Unmarshal(configJSON, &userConfigs); err != nil {
+	gatewayConfig, userConfigs, err := parseConfig(configJSON)
+	if err != nil {
 		return fmt.Errorf("failed to parse user configs: %w", err)
 	}
-	g.userConfigs = userConfigs
-
-	if err := MergeUserConfigsIntoCatalog(g.catalog, g.instructionMap, g.userConfigs); err != nil {
+	if err := gatewayConfig.validateCompositeTools(); err != nil {
+		return fmt.Errorf("invalid composite tools: %w", err)
+	}
+	if err := gatewayConfig.validateResources(); err != nil {
+		return fmt.Errorf("invalid container resources: %w", err)
+	}
+	if err := gatewayConfig.validateSecrets(); err != nil {
+		return fmt.Errorf("invalid secrets settings: %w", err)
+	}
+	if err := gatewayConfig.validateImages(); err != nil {
+		return fmt.Errorf("invalid images settings: %w", err)
+	}
+	gatewayConfig.resolveServerKeys(g.instructionMap)
+	g.config = gatewayConfig
+
+	g.pool.Configure(g.config)
+	g.breakers = newBreakerRegistry(g.config.Breaker)
+	g.limiters = newLimiterRegistry(g.config)
+	g.rateLimiter = newRateLimiter(g.config)
+	g.cache = newResultCache(g.config.Cache)
+	g.spill = newSpillStore(time.Duration(g.config.Results.ResourceTTL), g.config.Results.MaxResourceBytes)
+	g.discoveryCache = newDiscoveryCache(g.config.Discovery.Cache)
+	g.registerMetaTools()
+	g.registerCompositeTools()
+
+	g.lazyMu.Lock()
+	g.lazyServers = make(map[string]*lazyServer)
+	g.lazyMu.Unlock()
+
+	// Merge into fresh copies so a reload doesn't build on the values of the previous config
+	merged := catalog.Catalog{Servers: cloneServers(g.pristine)}
+	if err := MergeUserConfigsIntoCatalog(merged, g.instructionMap, userConfigs); err != nil {
 		return fmt.Errorf("failed to merge user configs: %w", err)
 	}
+	g.setCatalogState(merged.Servers, userConfigs)
+	if g.pool.Runtime() == nil {
+		for name, server := range g.configuredServers() {
+			if server.Type == "server" && server.Image != "" {
+				return fmt.Errorf("server %s runs a container, but no container runtime is available", name)
+			}
+		}
+	}
+
+	// Pull the images of configured servers while the first servers start
+	g.startPrePull(ctx)
 
 	// Dynamically load tools after config is loaded
 	go g.dynamicallyListTools(ctx)
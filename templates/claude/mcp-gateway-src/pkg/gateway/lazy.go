@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// lazyServer tracks whether the stub tools of a lazily started server were replaced by the real ones
+type lazyServer struct {
+	mu       sync.Mutex
+	resolved bool
+}
+
+// lazyToolStubs returns the tools to register for a lazy server, preferring the discovery cache over the catalog
+func (g *Gateway) lazyToolStubs(serverName string, server catalog.Server) []*mcp.Tool {
+	tools, ok := g.discoveryCache.load(server)
+	if !ok {
+		return catalogToolStubs(serverName, server)
+	}
+
+	for _, tool := range tools {
+		tool.Name = fmt.Sprintf("%s-%s", serverName, tool.Name)
+	}
+	return tools
+}
+
+// catalogToolStubs builds tool definitions from the tools a catalog server declares
+func catalogToolStubs(serverName string, server catalog.Server) []*mcp.Tool {
+	stubs := make([]*mcp.Tool, 0, len(server.Tools))
+	for _, t := range server.Tools {
+		schema := map[string]any{"type": "object"}
+		if len(t.Parameters.Properties) > 0 {
+			schema["properties"] = t.Parameters.Properties.ToMap()
+			if len(t.Parameters.Required) > 0 {
+				schema["required"] = t.Parameters.Required
+			}
+		}
+
+		stubs = append(stubs, &mcp.Tool{
+			Name:        fmt.Sprintf("%s-%s", serverName, t.Name),
+			Description: t.Description,
+			InputSchema: schema,
+		})
+	}
+	return stubs
+}
+
+// registerLazyServer registers stub tools for a server without starting it.
+// It reports false when there is nothing to register, in which case the server must be discovered eagerly.
+func (g *Gateway) registerLazyServer(serverName string, catalogServer catalog.Server) bool {
+	stubs := g.lazyToolStubs(serverName, catalogServer)
+	if len(stubs) == 0 {
+		return false
+	}
+
+	g.lazyMu.Lock()
+	g.lazyServers[serverName] = &lazyServer{}
+	g.lazyMu.Unlock()
+
+	handler := g.lazyToolHandler(serverName, catalogServer)
+	for _, tool := range stubs {
+		g.addTool(serverName, tool, handler)
+	}
+
+	zap.L().Info("Registered tool stubs, server starts on first call",
+		zap.String("component", "TOOLS"),
+		zap.String("server", serverName),
+		zap.Int("tools", len(stubs)))
+	return true
+}
+
+// lazyToolHandler starts the server on the first call, swaps the stubs for the real tools and forwards the call
+func (g *Gateway) lazyToolHandler(serverName string, catalogServer catalog.Server) mcp.ToolHandler {
+	handler := g.createToolHandler(serverName, catalogServer)
+
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		release, err := g.resolveLazyServer(ctx, serverName, catalogServer)
+		if err != nil {
+			return &mcp.CallToolResult{}, fmt.Errorf("failed to start %s: %w", serverName, err)
+		}
+		// Keep the discovery session pooled so the call below reuses it instead of starting the server again
+		defer release()
+
+		if _, ok := g.tools.get(req.Params.Name); !ok {
+			return gatewayError(errCodeUnknownTool,
+				fmt.Sprintf("MCP server %q does not provide the tool %q", serverName, req.Params.Name),
+				map[string]any{"server": serverName, "tool": req.Params.Name}), nil
+		}
+		return handler(ctx, req)
+	}
+}
+
+// resolveLazyServer replaces the stub tools of a server with the tools it actually lists.
+// The returned function releases the session opened for the discovery.
+func (g *Gateway) resolveLazyServer(ctx context.Context, serverName string, catalogServer catalog.Server) (func(), error) {
+	noop := func() {}
+
+	g.lazyMu.Lock()
+	lazy, ok := g.lazyServers[serverName]
+	g.lazyMu.Unlock()
+	if !ok {
+		return noop, nil
+	}
+
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+	if lazy.resolved {
+		return noop, nil
+	}
+
+	sessionID := getSessionID(ctx)
+	g.prePull.wait(ctx, serverName)
+	session, err := g.pool.Acquire(ctx, serverName, sessionID, catalogServer)
+	if err != nil {
+		return noop, err
+	}
+	release := func() { g.pool.Release(serverName, sessionID) }
+
+	if err := g.registerSessionTools(ctx, serverName, catalogServer, session); err != nil {
+		release()
+		return noop, err
+	}
+
+	lazy.resolved = true
+	zap.L().Info("Server started on first call, real tools registered",
+		zap.String("component", "TOOLS"),
+		zap.String("server", serverName),
+		zap.Int("tools", len(g.tools.namesForServer(serverName))))
+
+	return release, nil
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errQueueFull    = errors.New("queue is full")
+	errQueueTimeout = errors.New("timed out waiting in queue")
+)
+
+// concurrencyLimiter bounds the parallel tool calls to one server and queues the rest in FIFO order
+type concurrencyLimiter struct {
+	mu      sync.Mutex
+	cfg     ConcurrencyConfig
+	active  int
+	waiters *list.List // of chan struct{}, closed when the waiter is handed a slot
+
+	maxQueued int
+	rejected  int
+	timedOut  int
+}
+
+// QueueStatus describes the concurrency limiter of a server for the status endpoint
+type QueueStatus struct {
+	Server        string `json:"server"`
+	MaxConcurrent int    `json:"maxConcurrent"`
+	Active        int    `json:"active"`
+	Queued        int    `json:"queued"`
+	MaxQueued     int    `json:"maxQueued"` // Highest queue depth seen, useful to size the limit
+	Rejected      int    `json:"rejected"`
+	TimedOut      int    `json:"timedOut"`
+}
+
+// acquire waits for a free slot and returns the function that gives it back
+func (l *concurrencyLimiter) acquire(ctx context.Context) (func(), error) {
+	l.mu.Lock()
+	if l.active < l.cfg.MaxConcurrent && l.waiters.Len() == 0 {
+		l.active++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+
+	if l.waiters.Len() >= l.cfg.QueueSize {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, errQueueFull
+	}
+
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.maxQueued = max(l.maxQueued, l.waiters.Len())
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(time.Duration(l.cfg.QueueTimeout))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-timeout:
+		if l.leave(elem, ready) {
+			return l.release, nil
+		}
+		l.mu.Lock()
+		l.timedOut++
+		l.mu.Unlock()
+		return nil, errQueueTimeout
+	case <-ctx.Done():
+		if l.leave(elem, ready) {
+			l.release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// leave removes a waiter from the queue, reporting true if it was handed a slot in the meantime
+func (l *concurrencyLimiter) leave(elem *list.Element, ready chan struct{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return true
+	default:
+		l.waiters.Remove(elem)
+		return false
+	}
+}
+
+// release hands the slot to the oldest waiter or frees it
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if front := l.waiters.Front(); front != nil {
+		l.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	l.active--
+}
+
+// status returns a snapshot of the limiter
+func (l *concurrencyLimiter) status(serverName string) QueueStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return QueueStatus{
+		Server:        serverName,
+		MaxConcurrent: l.cfg.MaxConcurrent,
+		Active:        l.active,
+		Queued:        l.waiters.Len(),
+		MaxQueued:     l.maxQueued,
+		Rejected:      l.rejected,
+		TimedOut:      l.timedOut,
+	}
+}
+
+// limiterRegistry lazily creates one concurrency limiter per server with a configured limit
+type limiterRegistry struct {
+	mu       sync.Mutex
+	cfg      GatewayConfig
+	limiters map[string]*concurrencyLimiter
+}
+
+// newLimiterRegistry creates an empty registry reading limits from the given config
+func newLimiterRegistry(cfg GatewayConfig) *limiterRegistry {
+	return &limiterRegistry{
+		cfg:      cfg,
+		limiters: make(map[string]*concurrencyLimiter),
+	}
+}
+
+// get returns the limiter of a server, or nil if its concurrency is unlimited
+func (r *limiterRegistry) get(serverName string) *concurrencyLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.limiters[serverName]; ok {
+		return l
+	}
+
+	cfg := r.cfg.ConcurrencyFor(serverName)
+	if cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+
+	l := &concurrencyLimiter{cfg: cfg, waiters: list.New()}
+	r.limiters[serverName] = l
+	return l
+}
+
+// status returns the state of every limiter in use
+func (r *limiterRegistry) status() []QueueStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]QueueStatus, 0, len(r.limiters))
+	for name, l := range r.limiters {
+		statuses = append(statuses, l.status(name))
+	}
+	return statuses
+}
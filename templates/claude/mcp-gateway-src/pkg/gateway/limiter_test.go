@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerConcurrencyOverrideKeepsGlobalQueue(t *testing.T) {
+	cfg, _, err := parseConfig([]byte(`{"gateway": {"servers": {"sqlite": {"concurrency": {"maxConcurrent": 1}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := cfg.ConcurrencyFor("sqlite")
+	want := ConcurrencyConfig{MaxConcurrent: 1, QueueSize: 100, QueueTimeout: Duration(30 * time.Second)}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// A call above the limit waits in the queue for the running one instead of being rejected
+	limiter := newLimiterRegistry(cfg).get("sqlite")
+	release, err := limiter.acquire(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := limiter.acquire(t.Context())
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("second call didn't wait for the first: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued call failed: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// Overflow strategies for results above the size limit
+const (
+	overflowTruncate = "truncate"
+	overflowResource = "resource"
+)
+
+// bytesPerToken is the rough ratio used to turn token limits into byte limits
+const bytesPerToken = 4
+
+// spilledResultPrefix is the URI prefix of results moved to gateway-hosted resources
+const spilledResultPrefix = "gateway://results/"
+
+// unlimitedResultsKey marks calls whose results are consumed by the gateway itself, like composite steps
+const unlimitedResultsKey contextKey = "unlimitedResults"
+
+// byteLimit returns the effective byte budget of a limit, 0 meaning unlimited
+func (l ResultLimit) byteLimit() int {
+	limit := l.MaxBytes
+	if l.MaxTokens > 0 && (limit == 0 || l.MaxTokens*bytesPerToken < limit) {
+		limit = l.MaxTokens * bytesPerToken
+	}
+	return limit
+}
+
+// contentSize returns the payload size of a content item as seen by the client
+func contentSize(content mcp.Content) int {
+	switch c := content.(type) {
+	case *mcp.TextContent:
+		return len(c.Text)
+	case *mcp.ImageContent:
+		return len(c.Data)
+	case *mcp.AudioContent:
+		return len(c.Data)
+	case *mcp.EmbeddedResource:
+		if c.Resource != nil {
+			return len(c.Resource.Text) + len(c.Resource.Blob)
+		}
+	}
+	return 0
+}
+
+// structuredSize returns the size of structured content as sent to the client
+func structuredSize(structured any) int {
+	if structured == nil {
+		return 0
+	}
+	data, err := json.Marshal(structured)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// limitResult applies the configured size limit of a tool to its result, content and structured content together.
+// Above the limit structured content is dropped, or spilled with the content, since it can't be cut meaningfully.
+// Results spilled to a resource can only be read by the given session.
+func (g *Gateway) limitResult(ctx context.Context, session *mcp.ServerSession, serverName string, toolName string, result *mcp.CallToolResult) *mcp.CallToolResult {
+	if result == nil || ctx.Value(unlimitedResultsKey) != nil {
+		return result
+	}
+
+	limit := g.config.ResultLimitFor(serverName, toolName)
+	budget := limit.byteLimit()
+	if budget <= 0 {
+		return result
+	}
+
+	contentTotal := 0
+	for _, content := range result.Content {
+		contentTotal += contentSize(content)
+	}
+	structuredBytes := structuredSize(result.StructuredContent)
+	total := contentTotal + structuredBytes
+	if total <= budget {
+		return result
+	}
+
+	limited := *result
+	limited.StructuredContent = nil
+	content := result.Content
+	if contentTotal > budget {
+		content = truncateContent(result.Content, budget, contentTotal)
+	}
+	overflow := map[string]any{"bytes": total}
+	if structuredBytes > 0 {
+		overflow["structuredContentBytes"] = structuredBytes
+	}
+
+	if limit.Strategy == overflowResource {
+		uri, err := g.spill.store(session, result.Content, result.StructuredContent)
+		if err == nil {
+			limited.Content = append(content,
+				&mcp.ResourceLink{
+					URI:         uri,
+					Name:        toolName + " result",
+					Description: fmt.Sprintf("Full %d byte result of %s, read it with resources/read", total, toolName),
+					MIMEType:    spillMIMEType(result.Content, result.StructuredContent),
+					Size:        int64Ptr(int64(total)),
+				})
+			overflow["strategy"] = overflowResource
+			overflow["uri"] = uri
+			setGatewayMeta(&limited, "overflow", overflow)
+			return &limited
+		}
+		zap.L().Warn("Failed to spill result, truncating instead", zap.String("component", "LIMITS"), zap.String("tool", toolName), zap.Error(err))
+	}
+
+	limited.Content = content
+	overflow["strategy"] = overflowTruncate
+	overflow["limit"] = budget
+	setGatewayMeta(&limited, "overflow", overflow)
+	return &limited
+}
+
+// truncateContent keeps content items within the byte budget and marks what was cut
+func truncateContent(contents []mcp.Content, budget int, total int) []mcp.Content {
+	kept := make([]mcp.Content, 0, len(contents)+1)
+	remaining := budget
+	omitted := 0
+
+	for _, content := range contents {
+		size := contentSize(content)
+		if size <= remaining {
+			kept = append(kept, content)
+			remaining -= size
+			continue
+		}
+
+		// Only text can be cut, binary items are dropped as a whole
+		if text, ok := content.(*mcp.TextContent); ok && remaining > 0 {
+			cut := text.Text[:remaining]
+			for !utf8.ValidString(cut) {
+				cut = cut[:len(cut)-1]
+			}
+			kept = append(kept, &mcp.TextContent{Text: cut, Meta: text.Meta, Annotations: text.Annotations})
+			omitted += size - len(cut)
+			remaining = 0
+			continue
+		}
+		omitted += size
+	}
+
+	kept = append(kept, &mcp.TextContent{
+		Text: fmt.Sprintf("\n[... %d of %d bytes truncated by the gateway ...]", omitted, total),
+	})
+	return kept
+}
+
+// spillMIMEType returns the MIME type used for a spilled result
+func spillMIMEType(contents []mcp.Content, structured any) string {
+	if structured != nil {
+		return "application/json"
+	}
+	for _, content := range contents {
+		if _, ok := content.(*mcp.TextContent); !ok {
+			return "application/json"
+		}
+	}
+	return "text/plain"
+}
+
+// int64Ptr returns a pointer to v
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// spilledResult is an oversized result kept for the session whose call produced it
+type spilledResult struct {
+	owner    *mcp.ServerSession
+	mimeType string
+	payload  string
+	timer    *time.Timer
+}
+
+// spillStore keeps oversized results until they expire, readable only by the session that made the call.
+// Results are served through one resource template, so storing one doesn't notify every session.
+type spillStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxBytes int
+	bytes    int
+	results  map[string]*spilledResult // Keyed by resource URI
+	order    []string                  // Resource URIs, oldest first
+}
+
+// newSpillStore creates a store whose results live for ttl and take at most maxBytes together
+func newSpillStore(ttl time.Duration, maxBytes int) *spillStore {
+	return &spillStore{ttl: ttl, maxBytes: maxBytes, results: make(map[string]*spilledResult)}
+}
+
+// spillTemplate returns the resource template spilled results are read through
+func spillTemplate() *mcp.ResourceTemplate {
+	return &mcp.ResourceTemplate{
+		URITemplate: spilledResultPrefix + "{id}",
+		Name:        "gateway-results",
+		Description: "Oversized tool results kept by the gateway for the session that made the call",
+	}
+}
+
+// store keeps the full content for the calling session and returns its resource URI.
+// Structured content is kept next to the content in one JSON document.
+// The oldest results are evicted when the store would exceed its byte limit.
+func (s *spillStore) store(owner *mcp.ServerSession, contents []mcp.Content, structured any) (string, error) {
+	mimeType := spillMIMEType(contents, structured)
+
+	var payload string
+	switch {
+	case mimeType == "text/plain":
+		texts := make([]string, 0, len(contents))
+		for _, content := range contents {
+			texts = append(texts, content.(*mcp.TextContent).Text)
+		}
+		payload = strings.Join(texts, "\n")
+	case structured != nil:
+		data, err := json.Marshal(map[string]any{"content": contents, "structuredContent": structured})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		payload = string(data)
+	default:
+		data, err := json.Marshal(contents)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		payload = string(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && len(payload) > s.maxBytes {
+		return "", fmt.Errorf("result of %d bytes exceeds the %d byte spill limit", len(payload), s.maxBytes)
+	}
+	for s.maxBytes > 0 && s.bytes+len(payload) > s.maxBytes && len(s.order) > 0 {
+		s.removeLocked(s.order[0])
+	}
+
+	uri := spilledResultPrefix + uuid.New().String()
+	s.results[uri] = &spilledResult{
+		owner:    owner,
+		mimeType: mimeType,
+		payload:  payload,
+		timer:    time.AfterFunc(s.ttl, func() { s.remove(uri) }),
+	}
+	s.order = append(s.order, uri)
+	s.bytes += len(payload)
+	return uri, nil
+}
+
+// remove drops an expired result
+func (s *spillStore) remove(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(uri)
+}
+
+// removeLocked drops a result, s.mu must be held
+func (s *spillStore) removeLocked(uri string) {
+	result, ok := s.results[uri]
+	if !ok {
+		return
+	}
+	result.timer.Stop()
+	delete(s.results, uri)
+	s.order = slices.DeleteFunc(s.order, func(existing string) bool { return existing == uri })
+	s.bytes -= len(result.payload)
+}
+
+// read serves a spilled result to the session that stored it, other sessions see it as missing
+func (s *spillStore) read(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+
+	s.mu.Lock()
+	result, ok := s.results[uri]
+	s.mu.Unlock()
+	if !ok || result.owner != req.Session {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: result.mimeType, Text: result.payload}},
+	}, nil
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newLimitedGateway(strategy string) *Gateway {
+	cfg := DefaultGatewayConfig()
+	cfg.Results.MaxBytes = 100
+	cfg.Results.Strategy = strategy
+	return &Gateway{config: cfg, spill: newSpillStore(time.Minute, 0)}
+}
+
+func oversizedStructuredResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: "rows"}},
+		StructuredContent: map[string]any{"rows": strings.Repeat("x", 200)},
+	}
+}
+
+func TestLimitResultCountsStructuredContent(t *testing.T) {
+	g := newLimitedGateway(overflowTruncate)
+
+	limited := g.limitResult(t.Context(), nil, "sqlite", "query", oversizedStructuredResult())
+	if limited.StructuredContent != nil {
+		t.Error("oversized structured content was passed through")
+	}
+	if text := limited.Content[0].(*mcp.TextContent).Text; text != "rows" {
+		t.Errorf("content within the limit was changed to %q", text)
+	}
+	overflow, _ := limited.Meta[gatewayMetaKey].(map[string]any)["overflow"].(map[string]any)
+	if overflow["structuredContentBytes"] == nil {
+		t.Errorf("overflow meta %v doesn't report the dropped structured content", overflow)
+	}
+
+	small := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "rows"}}, StructuredContent: map[string]any{"rows": 1}}
+	if g.limitResult(t.Context(), nil, "sqlite", "query", small) != small {
+		t.Error("result within the limit was changed")
+	}
+}
+
+func TestLimitResultSpillsStructuredContentWithText(t *testing.T) {
+	g := newLimitedGateway(overflowResource)
+
+	limited := g.limitResult(t.Context(), nil, "sqlite", "query", oversizedStructuredResult())
+	if limited.StructuredContent != nil {
+		t.Error("oversized structured content was passed through")
+	}
+	link, ok := limited.Content[len(limited.Content)-1].(*mcp.ResourceLink)
+	if !ok {
+		t.Fatalf("got %#v, want a link to the spilled result", limited.Content)
+	}
+
+	read, err := g.spill.read(context.Background(), &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: link.URI}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := read.Contents[0]
+	if payload.MIMEType != "application/json" || !strings.Contains(payload.Text, `"structuredContent":{"rows":"xxx`) || !strings.Contains(payload.Text, `"rows"`) {
+		t.Errorf("spilled %s payload %s doesn't hold content and structured content", payload.MIMEType, payload.Text)
+	}
+}
@@ -3,6 +3,7 @@ package gateway
 import (
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 
 	"github.com/docker/mcp-gateway/pkg/catalog"
@@ -42,6 +43,54 @@ func MergeUserConfigsIntoCatalog(cat catalog.Catalog, instructionMap Instruction
 	return nil
 }
 
+// cloneServer copies a catalog server deeply enough that merging a user config into the copy
+// leaves the original untouched
+func cloneServer(server catalog.Server) catalog.Server {
+	clone := server
+	clone.Secrets = slices.Clone(server.Secrets)
+	clone.Env = slices.Clone(server.Env)
+	clone.Command = slices.Clone(server.Command)
+	clone.Volumes = slices.Clone(server.Volumes)
+	clone.AllowHosts = slices.Clone(server.AllowHosts)
+	clone.Remote.Headers = maps.Clone(server.Remote.Headers)
+	if server.Config != nil {
+		clone.Config = make([]any, len(server.Config))
+		for i, config := range server.Config {
+			clone.Config[i] = cloneValue(config)
+		}
+	}
+	return clone
+}
+
+// cloneServers copies every server of a catalog with cloneServer
+func cloneServers(servers map[string]catalog.Server) map[string]catalog.Server {
+	clones := make(map[string]catalog.Server, len(servers))
+	for name, server := range servers {
+		clones[name] = cloneServer(server)
+	}
+	return clones
+}
+
+// cloneValue copies the maps and slices of a decoded YAML or JSON value
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		clone := make(map[string]any, len(v))
+		for key, item := range v {
+			clone[key] = cloneValue(item)
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	default:
+		return value
+	}
+}
+
 // mergeUserConfigWithInstructions merges user-provided config into the catalog server spec using instructions
 func mergeUserConfigWithInstructions(serviceName string, server catalog.Server, userConfig UserConfig, beautifiedName string, instructionMap InstructionMap) (catalog.Server, error) {
 	// Create a copy to avoid modifying original
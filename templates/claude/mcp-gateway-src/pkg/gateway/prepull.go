@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Pre-pull states of an image
+const (
+	prePullPending = "pending"
+	prePullPulling = "pulling"
+	prePullReady   = "ready"
+	prePullFailed  = "failed"
+)
+
+// PrePullConfig controls pulling the images of configured servers when the config is loaded
+type PrePullConfig struct {
+	Disabled    bool `json:"disabled,omitempty"`
+	Parallelism int  `json:"parallelism,omitempty"` // Images pulled at the same time
+}
+
+// PrePullStatus describes the progress of the startup pre-pull for the status endpoints
+type PrePullStatus struct {
+	Total   int                  `json:"total"`
+	Pending int                  `json:"pending"`
+	Pulling int                  `json:"pulling"`
+	Ready   int                  `json:"ready"`
+	Failed  int                  `json:"failed"`
+	Done    bool                 `json:"done"`
+	Images  []ImagePrePullStatus `json:"images,omitempty"`
+}
+
+// ImagePrePullStatus is the pre-pull state of the image of one server
+type ImagePrePullStatus struct {
+	Server   string `json:"server"`
+	Image    string `json:"image"`
+	State    string `json:"state"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// prePullEntry tracks the image of one server
+type prePullEntry struct {
+	server   string
+	image    string
+	images   transport.Images
+	state    string
+	err      error
+	duration time.Duration
+	done     chan struct{} // Closed once the pull finished, successfully or not
+}
+
+// prePuller pulls the images of configured servers in the background
+type prePuller struct {
+	mu      sync.Mutex
+	entries map[string]*prePullEntry // Keyed by server name
+}
+
+// newPrePuller tracks the images of servers, none of them is pulled before start
+func newPrePuller(servers map[string]catalog.Server, config GatewayConfig) *prePuller {
+	p := &prePuller{entries: make(map[string]*prePullEntry)}
+	for name, server := range servers {
+		if server.Type != "server" || server.Image == "" {
+			continue
+		}
+		images := config.ImagesFor(name)
+		if images.PullPolicy == transport.PullNever {
+			continue
+		}
+		p.entries[name] = &prePullEntry{
+			server: name,
+			image:  server.Image,
+			images: images,
+			state:  prePullPending,
+			done:   make(chan struct{}),
+		}
+	}
+	return p
+}
+
+// start pulls the tracked images with at most parallelism pulls at the same time
+func (p *prePuller) start(ctx context.Context, runtime containers.Runtime, parallelism int) {
+	if len(p.entries) == 0 {
+		return
+	}
+	if parallelism <= 0 {
+		parallelism = defaultPullParallelism
+	}
+
+	zap.L().Info("Pre-pulling server images",
+		zap.String("component", "PULL"),
+		zap.Int("images", len(p.entries)),
+		zap.Int("parallelism", parallelism))
+
+	go func() {
+		var eg errgroup.Group
+		eg.SetLimit(parallelism)
+		for _, entry := range p.sortedEntries() {
+			eg.Go(func() error {
+				p.mu.Lock()
+				entry.state = prePullPulling
+				p.mu.Unlock()
+
+				start := time.Now()
+				_, err := transport.EnsureImage(ctx, runtime, entry.image, entry.images)
+				p.finish(entry, err, time.Since(start))
+				return nil
+			})
+		}
+		eg.Wait()
+
+		status := p.status()
+		zap.L().Info("Image pre-pull finished",
+			zap.String("component", "PULL"),
+			zap.Int("ready", status.Ready),
+			zap.Int("failed", status.Failed))
+	}()
+}
+
+// finish records the result of pulling the image of one server
+func (p *prePuller) finish(entry *prePullEntry, err error, duration time.Duration) {
+	p.mu.Lock()
+	entry.duration = duration
+	if err != nil {
+		entry.state = prePullFailed
+		entry.err = err
+	} else {
+		entry.state = prePullReady
+	}
+	p.mu.Unlock()
+	close(entry.done)
+
+	if err != nil {
+		// Session creation pulls the image again and reports the failure to the caller
+		zap.L().Warn("Failed to pre-pull image",
+			zap.String("component", "PULL"),
+			zap.String("server", entry.server),
+			zap.String("image", entry.image),
+			zap.Error(err))
+	}
+}
+
+// wait blocks until the pre-pull of a server's image finished, servers without a pre-pull return immediately
+func (p *prePuller) wait(ctx context.Context, serverName string) {
+	if p == nil {
+		return
+	}
+	entry, ok := p.entries[serverName]
+	if !ok {
+		return
+	}
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+	}
+}
+
+// pulled reports whether the pre-pull pulled an image in this process
+func (p *prePuller) pulled(image string) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range p.entries {
+		if entry.image == image && entry.state == prePullReady {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedEntries returns the tracked images ordered by server name
+func (p *prePuller) sortedEntries() []*prePullEntry {
+	entries := make([]*prePullEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *prePullEntry) int { return strings.Compare(a.server, b.server) })
+	return entries
+}
+
+// status returns the pre-pull progress
+func (p *prePuller) status() PrePullStatus {
+	status := PrePullStatus{Done: true}
+	if p == nil {
+		return status
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, entry := range p.sortedEntries() {
+		image := ImagePrePullStatus{Server: entry.server, Image: entry.image, State: entry.state}
+		switch entry.state {
+		case prePullPending:
+			status.Pending++
+		case prePullPulling:
+			status.Pulling++
+		case prePullReady:
+			status.Ready++
+		case prePullFailed:
+			status.Failed++
+			image.Error = entry.err.Error()
+		}
+		if entry.duration > 0 {
+			image.Duration = entry.duration.Round(time.Millisecond).String()
+		}
+		status.Images = append(status.Images, image)
+	}
+	status.Total = len(p.entries)
+	status.Done = status.Pending == 0 && status.Pulling == 0
+	return status
+}
+
+// configuredServers returns the catalog servers of the user config keyed by server name
+func (g *Gateway) configuredServers() map[string]catalog.Server {
+	servers := make(map[string]catalog.Server)
+	for configKey := range g.userConfigs {
+		if strings.HasPrefix(configKey, "github/") {
+			continue
+		}
+		serverName, ok := GetServerNameFromInstructions(g.instructionMap, configKey)
+		if !ok {
+			continue
+		}
+		if server, ok := g.catalog.Servers[serverName]; ok {
+			servers[serverName] = server
+		}
+	}
+	return servers
+}
+
+// startPrePull starts pulling the images of the configured servers in the background
+func (g *Gateway) startPrePull(ctx context.Context) {
+	if g.config.PrePull.Disabled || g.pool.Runtime() == nil {
+		g.prePull = nil
+		g.pool.setPrePuller(nil)
+		return
+	}
+	g.prePull = newPrePuller(g.configuredServers(), g.config)
+	g.pool.setPrePuller(g.prePull)
+	g.prePull.start(context.WithoutCancel(ctx), g.pool.Runtime(), g.config.PrePull.Parallelism)
+}
@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPrePulledImagesAreNotPulledAgainForSessions(t *testing.T) {
+	const image = "mcp/echo:latest"
+	runtime := containers.NewFake()
+	runtime.AddImage(image, containers.FakeMCPServer(mcp.NewServer(&mcp.Implementation{Name: "echo"}, nil)), false)
+
+	cfg := DefaultGatewayConfig()
+	cfg.Images.PullPolicy = transport.PullAlways
+	pool := NewClientPool(runtime)
+	defer pool.Close()
+	pool.Configure(cfg)
+
+	server := catalog.Server{Name: "echo", Type: "server", Image: image}
+	prePull := newPrePuller(map[string]catalog.Server{"echo": server}, cfg)
+	pool.setPrePuller(prePull)
+	prePull.start(t.Context(), runtime, 1)
+	prePull.wait(t.Context(), "echo")
+
+	if _, err := pool.Acquire(t.Context(), "echo", "first", server); err != nil {
+		t.Fatal(err)
+	}
+	if pulls := runtime.Pulls(); !slices.Equal(pulls, []string{image}) {
+		t.Errorf("pulls after the first session: got %v, want only the pre-pull", pulls)
+	}
+
+	// Without a pre-pull the policy applies to every session again
+	pool.setPrePuller(nil)
+	if _, err := pool.Acquire(t.Context(), "echo", "second", server); err != nil {
+		t.Fatal(err)
+	}
+	if pulls := runtime.Pulls(); len(pulls) != 2 {
+		t.Errorf("pulls after the second session: got %v, want the image pulled again", pulls)
+	}
+}
@@ -2,70 +2,342 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
-	"e2b.dev/mcp-gateway/pkg/utils"
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
 )
 
-// PullImages resolves beautified service names to catalog servers and pulls their Docker images.
-// Only servers with type "server" and a non-empty Image are pulled. On the first error,
-// this function returns immediately with that error.
-func PullImages(ctx context.Context, catalogPaths []string, mappingPath string, beautifiedNames []string) error {
-	if len(beautifiedNames) == 0 {
-		return fmt.Errorf("no services specified to pull")
+// defaultPullParallelism is the number of images pulled at the same time when not configured
+const defaultPullParallelism = 4
+
+// imageServer is a catalog server whose Docker image is pulled or bundled
+type imageServer struct {
+	name   string
+	server catalog.Server
+}
+
+// ServerSelection selects catalog servers by beautified name, by catalog category or all of them
+type ServerSelection struct {
+	Names      []string // Beautified service names
+	Categories []string // Categories from the catalog metadata
+	All        bool     // Every server of the catalog
+}
+
+// empty reports whether the selection selects nothing
+func (s ServerSelection) empty() bool {
+	return len(s.Names) == 0 && len(s.Categories) == 0 && !s.All
+}
+
+// PullOptions controls how PullImages pulls
+type PullOptions struct {
+	Parallelism int       // Images pulled at the same time, defaults to 4
+	Progress    io.Writer // Receives one JSON progress event per line, nil logs instead
+}
+
+// PullFailure is an image that could not be pulled
+type PullFailure struct {
+	Server string
+	Image  string
+	Err    error
+}
+
+// PullError reports the images of a PullImages run that could not be pulled
+type PullError struct {
+	Total    int
+	Failures []PullFailure
+}
+
+// Error implements error
+func (e *PullError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to pull %d of %d images", len(e.Failures), e.Total)
+	for _, failure := range e.Failures {
+		fmt.Fprintf(&b, "\n  %s (%s): %v", failure.Server, failure.Image, failure.Err)
+	}
+	return b.String()
+}
+
+// pullEvent is one line of JSON progress output
+type pullEvent struct {
+	Event    string `json:"event"` // "skipped", "started", "pulled", "failed" or "summary"
+	Server   string `json:"server,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Total    int    `json:"total,omitempty"`
+	Pulled   int    `json:"pulled,omitempty"`
+	Failed   int    `json:"failed,omitempty"`
+	Skipped  int    `json:"skipped,omitempty"`
+}
+
+// pullReporter writes progress as JSON lines or log entries
+type pullReporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// report emits one progress event
+func (r *pullReporter) report(event pullEvent) {
+	if r.out == nil {
+		r.log(event)
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out.Write(append(data, '\n'))
+}
+
+// log emits a progress event as a log entry
+func (r *pullReporter) log(event pullEvent) {
+	fields := []zap.Field{zap.String("component", "PULL")}
+	if event.Server != "" {
+		fields = append(fields, zap.String("server", event.Server), zap.String("image", event.Image))
+	}
+
+	switch event.Event {
+	case "skipped":
+		zap.L().Info("Skipping service without image", append(fields, zap.String("reason", event.Reason))...)
+	case "pulled":
+		zap.L().Info("Image pulled", append(fields, zap.String("duration", event.Duration))...)
+	case "failed":
+		zap.L().Error("Failed to pull image", append(fields, zap.String("error", event.Error))...)
+	case "summary":
+		zap.L().Info("Pull finished", append(fields,
+			zap.Int("total", event.Total),
+			zap.Int("pulled", event.Pulled),
+			zap.Int("failed", event.Failed),
+			zap.Int("skipped", event.Skipped))...)
+	}
+}
+
+// PullImages resolves the selected catalog servers and pulls their Docker images in parallel.
+// Servers without an image (remote, poci) are skipped with a notice. A failed pull does not stop
+// the others, the failures are returned together as a *PullError.
+// The images settings of configJSON apply, except that an unset pull policy means "always".
+func PullImages(ctx context.Context, runtime containers.Runtime, catalogPaths []string, mappingPath string, configJSON []byte, selection ServerSelection, opts PullOptions) error {
+	reporter := &pullReporter{out: opts.Progress}
+
+	servers, skipped, gatewayConfig, err := resolveImageServers(ctx, catalogPaths, mappingPath, configJSON, selection)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		reporter.report(pullEvent{Event: "skipped", Server: s.name, Image: s.server.Image, Reason: skipReason(s.server)})
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultPullParallelism
+	}
+
+	var mu sync.Mutex
+	var failures []PullFailure
+
+	var eg errgroup.Group
+	eg.SetLimit(parallelism)
+	for _, s := range servers {
+		eg.Go(func() error {
+			images := gatewayConfig.ImagesFor(s.name)
+			if images.PullPolicy == "" {
+				images.PullPolicy = transport.PullAlways
+			}
+
+			reporter.report(pullEvent{Event: "started", Server: s.name, Image: s.server.Image})
+			start := time.Now()
+			if _, err := transport.EnsureImage(ctx, runtime, s.server.Image, images); err != nil {
+				mu.Lock()
+				failures = append(failures, PullFailure{Server: s.name, Image: s.server.Image, Err: err})
+				mu.Unlock()
+				reporter.report(pullEvent{Event: "failed", Server: s.name, Image: s.server.Image, Error: err.Error()})
+				return nil
+			}
+			reporter.report(pullEvent{
+				Event:    "pulled",
+				Server:   s.name,
+				Image:    s.server.Image,
+				Duration: time.Since(start).Round(time.Millisecond).String(),
+			})
+			return nil
+		})
+	}
+	eg.Wait()
+
+	reporter.report(pullEvent{
+		Event:   "summary",
+		Total:   len(servers),
+		Pulled:  len(servers) - len(failures),
+		Failed:  len(failures),
+		Skipped: len(skipped),
+	})
+
+	if len(failures) > 0 {
+		slices.SortFunc(failures, func(a, b PullFailure) int { return strings.Compare(a.Server, b.Server) })
+		return &PullError{Total: len(servers), Failures: failures}
+	}
+	return nil
+}
+
+// resolveImageServers maps a selection to the catalog servers whose images it needs, returning the
+// selected servers without an image separately, and loads the gateway settings of configJSON
+func resolveImageServers(ctx context.Context, catalogPaths []string, mappingPath string, configJSON []byte, selection ServerSelection) ([]imageServer, []imageServer, GatewayConfig, error) {
+	gatewayConfig := DefaultGatewayConfig()
+	if selection.empty() {
+		return nil, nil, gatewayConfig, fmt.Errorf("no services specified")
 	}
 
 	// Load instruction map and catalog once
 	instructionMap, err := LoadInstructionMap(mappingPath)
 	if err != nil {
-		return fmt.Errorf("failed to load instruction map: %w", err)
+		return nil, nil, gatewayConfig, fmt.Errorf("failed to load instruction map: %w", err)
 	}
 
 	cat, err := catalog.ReadFrom(ctx, catalogPaths)
 	if err != nil {
-		return fmt.Errorf("failed to read catalog: %w", err)
+		return nil, nil, gatewayConfig, fmt.Errorf("failed to read catalog: %w", err)
 	}
 
-	// Create one Docker client for all pulls with custom User-Agent
-	dockerClient, err := utils.NewDockerClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
+	if len(configJSON) > 0 {
+		if gatewayConfig, _, err = parseConfig(configJSON); err != nil {
+			return nil, nil, gatewayConfig, fmt.Errorf("failed to parse config: %w", err)
+		}
+		if err := gatewayConfig.validateImages(); err != nil {
+			return nil, nil, gatewayConfig, fmt.Errorf("invalid images settings: %w", err)
+		}
+		gatewayConfig.resolveServerKeys(instructionMap)
 	}
-	defer dockerClient.Close()
 
-	for _, beautified := range beautifiedNames {
+	var serverNames []string
+	for _, beautified := range selection.Names {
 		// Map beautified name to actual catalog server name
 		actualServerName, ok := GetServerNameFromInstructions(instructionMap, beautified)
 		if !ok {
-			return fmt.Errorf("could not resolve service name %q", beautified)
+			return nil, nil, gatewayConfig, fmt.Errorf("could not resolve service name %q", beautified)
+		}
+		if _, ok := cat.Servers[actualServerName]; !ok {
+			return nil, nil, gatewayConfig, fmt.Errorf("server %q not found in catalog", actualServerName)
 		}
+		serverNames = append(serverNames, actualServerName)
+	}
 
-		server, ok := cat.Servers[actualServerName]
-		if !ok {
-			return fmt.Errorf("server %q not found in catalog", actualServerName)
+	if selection.All {
+		for name := range cat.Servers {
+			serverNames = append(serverNames, name)
 		}
+	} else if len(selection.Categories) > 0 {
+		categories, err := readCatalogCategories(ctx, catalogPaths)
+		if err != nil {
+			return nil, nil, gatewayConfig, fmt.Errorf("failed to read catalog categories: %w", err)
+		}
+		matched := false
+		for name, category := range categories {
+			if _, ok := cat.Servers[name]; ok && slices.Contains(selection.Categories, category) {
+				serverNames = append(serverNames, name)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, nil, gatewayConfig, fmt.Errorf("no catalog server has category %s", strings.Join(selection.Categories, ", "))
+		}
+	}
 
-		// Only pull images for type "server"
-		if server.Type != "server" {
-			return fmt.Errorf("service %q is type %q; expected \"server\"", actualServerName, server.Type)
+	slices.Sort(serverNames)
+	serverNames = slices.Compact(serverNames)
+
+	var servers, skipped []imageServer
+	for _, name := range serverNames {
+		server := cat.Servers[name]
+		// Only servers of type "server" run an image
+		if server.Type != "server" || server.Image == "" {
+			skipped = append(skipped, imageServer{name: name, server: server})
+			continue
 		}
+		servers = append(servers, imageServer{name: name, server: server})
+	}
+
+	return servers, skipped, gatewayConfig, nil
+}
+
+// skipReason explains why a server has no image to pull
+func skipReason(server catalog.Server) string {
+	if server.Type != "server" {
+		return fmt.Sprintf("type %q does not run an image", server.Type)
+	}
+	return "no image in catalog"
+}
 
-		if server.Image == "" {
-			return fmt.Errorf("service %q has no image to pull", actualServerName)
+// catalogMetadata holds the catalog fields the catalog package does not decode
+type catalogMetadata struct {
+	Registry map[string]struct {
+		Metadata struct {
+			Category string `yaml:"category"`
+		} `yaml:"metadata"`
+	} `yaml:"registry"`
+}
+
+// readCatalogCategories returns the category of every catalog server that has one.
+// Later catalogs override earlier ones like in catalog.ReadFrom.
+func readCatalogCategories(ctx context.Context, catalogPaths []string) (map[string]string, error) {
+	categories := make(map[string]string)
+	for _, path := range catalogPaths {
+		data, err := readCatalogFile(ctx, path)
+		if err != nil {
+			return nil, err
 		}
 
-		// Pull the Docker image using shared helper
-		if err := utils.PullImage(ctx, server.Image, dockerClient); err != nil {
-			return fmt.Errorf("failed to pull image %q: %w", server.Image, err)
+		var metadata catalogMetadata
+		if err := yaml.Unmarshal(data, &metadata); err != nil {
+			return nil, fmt.Errorf("invalid catalog %s: %w", path, err)
+		}
+		for name, server := range metadata.Registry {
+			if server.Metadata.Category != "" {
+				categories[name] = server.Metadata.Category
+			}
 		}
+	}
+	return categories, nil
+}
 
-		zap.L().Info("Image pulled",
-			zap.String("component", "PULL"),
-			zap.String("server", actualServerName),
-			zap.String("image", server.Image))
+// readCatalogFile reads a catalog from a file or an http(s) URL, a missing file reads as empty
+func readCatalogFile(ctx context.Context, path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return data, err
 	}
 
-	return nil
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
 }
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxIdleBuckets is the bucket count above which idle, refilled buckets are dropped
+const maxIdleBuckets = 10000
+
+// Rate limit scopes, reported in rejections and on the status endpoint
+const (
+	rateScopeToken   = "token"
+	rateScopeSession = "session"
+	rateScopeServer  = "server"
+	rateScopeTool    = "tool"
+)
+
+// tokenBucket refills continuously at rate tokens per second up to burst tokens
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.limit.perSecond(), float64(b.limit.burst()))
+	b.last = now
+}
+
+// wait returns how long until the bucket holds a whole token
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.perSecond() * float64(time.Second))
+}
+
+// rateCheck names one bucket a call has to take a token from
+type rateCheck struct {
+	scope string
+	key   string
+	limit RateLimit
+}
+
+// rateLimitError describes the bucket that rejected a call
+type rateLimitError struct {
+	scope      string
+	limit      RateLimit
+	retryAfter time.Duration
+}
+
+// rateLimiter holds the token buckets of all scopes
+type rateLimiter struct {
+	mu       sync.Mutex
+	cfg      GatewayConfig
+	buckets  map[string]*tokenBucket
+	rejected map[string]int // Rejections per scope
+}
+
+// RateLimitStatus reports rejections of a rate limit scope for the status endpoint
+type RateLimitStatus struct {
+	Scope    string `json:"scope"`
+	Rejected int    `json:"rejected"`
+}
+
+// newRateLimiter creates a limiter with the limits of the given config
+func newRateLimiter(cfg GatewayConfig) *rateLimiter {
+	return &rateLimiter{
+		cfg:      cfg,
+		buckets:  make(map[string]*tokenBucket),
+		rejected: make(map[string]int),
+	}
+}
+
+// checks lists the buckets a tool call is subject to
+func (l *rateLimiter) checks(serverName string, params *mcp.CallToolRequest, sessionID string) []rateCheck {
+	limits := l.cfg.RateLimits
+	var checks []rateCheck
+
+	if limits.PerToken != nil {
+		checks = append(checks, rateCheck{rateScopeToken, tokenKey(params), *limits.PerToken})
+	}
+	if limits.PerSession != nil {
+		checks = append(checks, rateCheck{rateScopeSession, sessionID, *limits.PerSession})
+	}
+	if limit := l.cfg.RateLimitFor(serverName); limit != nil {
+		checks = append(checks, rateCheck{rateScopeServer, serverName, *limit})
+	}
+	if limit, ok := limits.Tools[params.Params.Name]; ok {
+		checks = append(checks, rateCheck{rateScopeTool, params.Params.Name, limit})
+	} else if limits.PerTool != nil {
+		checks = append(checks, rateCheck{rateScopeTool, params.Params.Name, *limits.PerTool})
+	}
+
+	return checks
+}
+
+// allow takes one token from every bucket, or none if any of them is empty
+func (l *rateLimiter) allow(checks []rateCheck) *rateLimitError {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.buckets) > maxIdleBuckets {
+		l.prune(now)
+	}
+
+	buckets := make([]*tokenBucket, len(checks))
+	var rejection *rateLimitError
+	for i, check := range checks {
+		key := check.scope + "\x00" + check.key
+		bucket, ok := l.buckets[key]
+		if !ok {
+			bucket = &tokenBucket{limit: check.limit, tokens: float64(check.limit.burst()), last: now}
+			l.buckets[key] = bucket
+		}
+		bucket.refill(now)
+		buckets[i] = bucket
+
+		// Report the bucket that takes longest to recover so the retry hint is sufficient
+		if wait := bucket.wait(); wait > 0 && (rejection == nil || wait > rejection.retryAfter) {
+			rejection = &rateLimitError{scope: check.scope, limit: check.limit, retryAfter: wait}
+		}
+	}
+
+	if rejection != nil {
+		l.rejected[rejection.scope]++
+		return rejection
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return nil
+}
+
+// prune drops buckets that are full again, they are recreated on demand
+func (l *rateLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.limit.burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// status returns the rejection counters per scope
+func (l *rateLimiter) status() []RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	statuses := make([]RateLimitStatus, 0, len(l.rejected))
+	for scope, rejected := range l.rejected {
+		statuses = append(statuses, RateLimitStatus{Scope: scope, Rejected: rejected})
+	}
+	return statuses
+}
+
+// tokenKey identifies the auth token of a request without keeping the token itself
+func tokenKey(params *mcp.CallToolRequest) string {
+	if params.Extra == nil || params.Extra.Header == nil {
+		return "anonymous"
+	}
+
+	token := params.Extra.Header.Get("Authorization")
+	token = strings.TrimPrefix(token, "Bearer ")
+	if token == "" {
+		return "anonymous"
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
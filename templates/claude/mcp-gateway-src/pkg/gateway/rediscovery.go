@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// failedDiscovery tracks a server whose tools failed to load and when to try again
+type failedDiscovery struct {
+	server      catalog.Server
+	attempts    int
+	nextAttempt time.Time
+	lastError   string
+}
+
+// DiscoveryStatus describes a server waiting for background rediscovery
+type DiscoveryStatus struct {
+	Server      string    `json:"server"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError"`
+}
+
+// markDiscoveryFailed records a failed discovery and makes sure the rediscovery loop is running
+func (g *Gateway) markDiscoveryFailed(ctx context.Context, serverName string, catalogServer catalog.Server, err error) {
+	g.discoveryMu.Lock()
+	defer g.discoveryMu.Unlock()
+
+	if g.config.Rediscovery.Disabled {
+		return
+	}
+
+	failed, ok := g.failedDiscoveries[serverName]
+	if !ok {
+		failed = &failedDiscovery{}
+		g.failedDiscoveries[serverName] = failed
+	}
+	failed.server = catalogServer
+	failed.attempts++
+	failed.lastError = err.Error()
+	failed.nextAttempt = time.Now().Add(g.config.Rediscovery.Backoff.Delay(failed.attempts))
+
+	if !g.rediscovering {
+		g.rediscovering = true
+		go g.rediscoveryLoop(context.WithoutCancel(ctx))
+	}
+}
+
+// clearDiscoveryFailure forgets a server once its tools were registered
+func (g *Gateway) clearDiscoveryFailure(serverName string) {
+	g.discoveryMu.Lock()
+	defer g.discoveryMu.Unlock()
+
+	if _, ok := g.failedDiscoveries[serverName]; ok {
+		delete(g.failedDiscoveries, serverName)
+		zap.L().Info("Tools discovered after earlier failure", zap.String("component", "TOOLS"), zap.String("server", serverName))
+	}
+}
+
+// rediscoveryLoop retries the discovery of failed servers until all of them registered their tools
+func (g *Gateway) rediscoveryLoop(ctx context.Context) {
+	for {
+		g.discoveryMu.Lock()
+		if len(g.failedDiscoveries) == 0 {
+			g.rediscovering = false
+			g.discoveryMu.Unlock()
+			return
+		}
+
+		now := time.Now()
+		next := now.Add(time.Minute)
+		due := make(map[string]catalog.Server)
+		for name, failed := range g.failedDiscoveries {
+			if !failed.nextAttempt.After(now) {
+				due[name] = failed.server
+			} else if failed.nextAttempt.Before(next) {
+				next = failed.nextAttempt
+			}
+		}
+		g.discoveryMu.Unlock()
+
+		for serverName, catalogServer := range due {
+			zap.L().Info("Retrying tool discovery", zap.String("component", "TOOLS"), zap.String("server", serverName))
+			g.discoverServer(ctx, serverName, uuid.New().String(), catalogServer)
+		}
+
+		if len(due) == 0 && !sleepContext(ctx, time.Until(next)) {
+			return
+		}
+	}
+}
+
+// discoveryStatus returns the servers currently waiting for rediscovery
+func (g *Gateway) discoveryStatus() []DiscoveryStatus {
+	g.discoveryMu.Lock()
+	defer g.discoveryMu.Unlock()
+
+	statuses := make([]DiscoveryStatus, 0, len(g.failedDiscoveries))
+	for name, failed := range g.failedDiscoveries {
+		statuses = append(statuses, DiscoveryStatus{
+			Server:      name,
+			Attempts:    failed.attempts,
+			NextAttempt: failed.nextAttempt,
+			LastError:   failed.lastError,
+		})
+	}
+	return statuses
+}
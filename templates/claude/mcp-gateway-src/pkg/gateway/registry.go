@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// registeredTool is a backend tool as exposed by the gateway
+type registeredTool struct {
+	serverName string
+	tool       *mcp.Tool // Tool definition with the gateway-prefixed name
+	handler    mcp.ToolHandler
+	input      *jsonschema.Resolved // Compiled input schema, nil if it can't be validated
+	output     *jsonschema.Resolved // Compiled output schema, nil if the tool declares none
+}
+
+// toolRegistry indexes the exposed tools by name so the gateway can inspect their definitions
+type toolRegistry struct {
+	mu      sync.RWMutex
+	tools   map[string]*registeredTool
+	version int // Incremented on every change so derived indexes know when to rebuild
+}
+
+// newToolRegistry creates an empty tool registry
+func newToolRegistry() *toolRegistry {
+	return &toolRegistry{tools: make(map[string]*registeredTool)}
+}
+
+// add registers a tool or replaces a tool of the same server. A name taken by another server is
+// left to that server, add then returns the owner and false.
+func (r *toolRegistry) add(t *registeredTool) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.tools[t.tool.Name]; ok && existing.serverName != t.serverName {
+		return existing.serverName, false
+	}
+	r.tools[t.tool.Name] = t
+	r.version++
+	return t.serverName, true
+}
+
+// get returns the tool with the given exposed name
+func (r *toolRegistry) get(name string) (*registeredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// remove unregisters the given tools
+func (r *toolRegistry) remove(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		delete(r.tools, name)
+	}
+	r.version++
+}
+
+// removeServer unregisters all tools of a server and returns their names
+func (r *toolRegistry) removeServer(serverName string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed []string
+	for name, t := range r.tools {
+		if t.serverName == serverName {
+			removed = append(removed, name)
+			delete(r.tools, name)
+		}
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		r.version++
+	}
+	return removed
+}
+
+// namesForServer returns the sorted names of a server's registered tools
+func (r *toolRegistry) namesForServer(serverName string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for name, t := range r.tools {
+		if t.serverName == serverName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// list returns all registered tools sorted by name, together with the registry version
+func (r *toolRegistry) list() ([]*registeredTool, int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]*registeredTool, 0, len(r.tools))
+	for _, t := range r.tools {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].tool.Name < tools[j].tool.Name })
+	return tools, r.version
+}
+
+// addTool registers a backend tool with the registry and the MCP server
+func (g *Gateway) addTool(serverName string, tool *mcp.Tool, handler mcp.ToolHandler) {
+	if isMetaToolName(tool.Name) {
+		zap.L().Warn("Skipping tool named like a gateway meta-tool",
+			zap.String("component", "TOOLS"),
+			zap.String("server", serverName),
+			zap.String("tool", tool.Name))
+		return
+	}
+
+	sanitizeToolSchemas(tool)
+
+	owner, added := g.tools.add(&registeredTool{
+		serverName: serverName,
+		tool:       tool,
+		handler:    handler,
+		input:      compileToolSchema(tool.Name, "input", tool.InputSchema),
+		output:     compileToolSchema(tool.Name, "output", tool.OutputSchema),
+	})
+	if !added {
+		// The tool registered first keeps the name instead of being silently shadowed
+		zap.L().Warn("Skipping tool whose name is taken by another server",
+			zap.String("component", "TOOLS"),
+			zap.String("server", serverName),
+			zap.String("tool", tool.Name),
+			zap.String("owner", owner))
+		return
+	}
+
+	// In search mode backend tools are only reachable through the search meta-tools
+	if g.config.ToolMode != toolModeSearch {
+		g.server.AddTool(tool, handler)
+	}
+}
+
+// callRegisteredTool runs a registered tool through its regular handler on behalf of a meta-tool
+func (g *Gateway) callRegisteredTool(ctx context.Context, req *mcp.CallToolRequest, name string, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+	registered, ok := g.tools.get(name)
+	if !ok {
+		return gatewayError(errCodeUnknownTool, fmt.Sprintf("Unknown tool %q", name), map[string]any{"tool": name}), nil
+	}
+
+	return registered.handler(ctx, &mcp.CallToolRequest{
+		Session: req.Session,
+		Extra:   req.Extra,
+		Params: &mcp.CallToolParamsRaw{
+			Name:      name,
+			Arguments: arguments,
+		},
+	})
+}
+
+// sanitizeToolSchemas makes a backend tool acceptable to mcp.Server.AddTool, which panics on schemas
+// that are missing or not objects. Valid schemas are forwarded unchanged.
+func sanitizeToolSchemas(tool *mcp.Tool) {
+	if schemaType(tool.InputSchema) != "object" {
+		zap.L().Warn("Tool has no object input schema, accepting any object",
+			zap.String("component", "TOOLS"),
+			zap.String("tool", tool.Name))
+		tool.InputSchema = map[string]any{"type": "object"}
+	}
+
+	if tool.OutputSchema != nil && schemaType(tool.OutputSchema) != "object" {
+		zap.L().Warn("Dropping output schema that is not an object",
+			zap.String("component", "TOOLS"),
+			zap.String("tool", tool.Name))
+		tool.OutputSchema = nil
+	}
+}
+
+// schemaType returns the top-level "type" of a schema as decoded from a backend
+func schemaType(schema any) string {
+	m, ok := schema.(map[string]any)
+	if !ok {
+		return ""
+	}
+	t, _ := m["type"].(string)
+	return t
+}
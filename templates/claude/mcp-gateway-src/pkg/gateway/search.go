@@ -0,0 +1,266 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Names of the tools exposed in search mode
+const (
+	searchToolName = "gateway-search-tools"
+	callToolName   = "gateway-call-tool"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// nameWeight is how many times tool name terms count compared to description terms
+const nameWeight = 3
+
+// searchDocument is the indexed form of a tool
+type searchDocument struct {
+	tool   *registeredTool
+	terms  map[string]int
+	length int
+}
+
+// searchIndex is a BM25 index over the registered tools, rebuilt when the registry changes
+type searchIndex struct {
+	mu        sync.Mutex
+	version   int
+	docs      []searchDocument
+	docFreq   map[string]int
+	avgLength float64
+}
+
+// searchHit is a ranked tool returned by the search tool
+type searchHit struct {
+	Name        string  `json:"name"`
+	Server      string  `json:"server"`
+	Description string  `json:"description,omitempty"`
+	InputSchema any     `json:"inputSchema"`
+	Score       float64 `json:"score"`
+}
+
+// tokenize splits text into lower-case terms, breaking identifiers at case changes, dashes and underscores
+func tokenize(text string) []string {
+	var terms []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			terms = append(terms, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return terms
+}
+
+// schemaText collects property names and descriptions of a schema for indexing
+func schemaText(schema any, b *strings.Builder) {
+	m, ok := schema.(map[string]any)
+	if !ok {
+		return
+	}
+	if description, ok := m["description"].(string); ok {
+		b.WriteString(description)
+		b.WriteByte(' ')
+	}
+	if properties, ok := m["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			b.WriteString(name)
+			b.WriteByte(' ')
+			schemaText(property, b)
+		}
+	}
+	if items, ok := m["items"]; ok {
+		schemaText(items, b)
+	}
+}
+
+// refresh rebuilds the index if the registry changed since the last build
+func (idx *searchIndex) refresh(registry *toolRegistry) {
+	tools, version := registry.list()
+	if version == idx.version && idx.docs != nil {
+		return
+	}
+
+	idx.docs = make([]searchDocument, 0, len(tools))
+	idx.docFreq = make(map[string]int)
+	total := 0
+
+	for _, t := range tools {
+		doc := searchDocument{tool: t, terms: make(map[string]int)}
+
+		for _, term := range tokenize(t.tool.Name) {
+			doc.terms[term] += nameWeight
+			doc.length += nameWeight
+		}
+
+		var text strings.Builder
+		text.WriteString(t.tool.Title)
+		text.WriteByte(' ')
+		text.WriteString(t.tool.Description)
+		text.WriteByte(' ')
+		schemaText(t.tool.InputSchema, &text)
+		for _, term := range tokenize(text.String()) {
+			doc.terms[term]++
+			doc.length++
+		}
+
+		for term := range doc.terms {
+			idx.docFreq[term]++
+		}
+		total += doc.length
+		idx.docs = append(idx.docs, doc)
+	}
+
+	idx.avgLength = 1
+	if len(idx.docs) > 0 {
+		idx.avgLength = max(float64(total)/float64(len(idx.docs)), 1)
+	}
+	idx.version = version
+}
+
+// search ranks the registered tools against the query
+func (idx *searchIndex) search(registry *toolRegistry, query string, limit int) []searchHit {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.refresh(registry)
+
+	queryTerms := tokenize(query)
+	n := float64(len(idx.docs))
+
+	var hits []searchHit
+	for _, doc := range idx.docs {
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(doc.terms[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/idx.avgLength))
+		}
+		if score > 0 {
+			hits = append(hits, searchHit{
+				Name:        doc.tool.tool.Name,
+				Server:      doc.tool.serverName,
+				Description: doc.tool.tool.Description,
+				InputSchema: doc.tool.tool.InputSchema,
+				Score:       math.Round(score*1000) / 1000,
+			})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// searchTools returns the definitions of the search-mode tools
+func searchTools(cfg SearchConfig) (*mcp.Tool, *mcp.Tool) {
+	search := &mcp.Tool{
+		Name: searchToolName,
+		Description: "Search the tools available through this gateway by keywords. " +
+			"Returns matching tool names with their descriptions and input schemas; call them with " + callToolName + ".",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "Keywords describing the task, e.g. \"web search news\""},
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": fmt.Sprintf("Maximum number of tools to return (default %d)", cfg.DefaultLimit),
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+
+	call := &mcp.Tool{
+		Name:        callToolName,
+		Description: "Call a tool found with " + searchToolName + " by its exact name.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tool":      map[string]any{"type": "string", "description": "Exact tool name returned by " + searchToolName},
+				"arguments": map[string]any{"type": "object", "description": "Arguments matching the tool's input schema"},
+			},
+			"required": []string{"tool"},
+		},
+	}
+
+	return search, call
+}
+
+// handleSearchTools ranks the registered tools for a query
+func (g *Gateway) handleSearchTools(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || strings.TrimSpace(args.Query) == "" {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs a non-empty query", searchToolName), nil), nil
+	}
+
+	limit := g.config.MetaTools.Search.DefaultLimit
+	if args.Limit > 0 {
+		limit = args.Limit
+	}
+	limit = max(limit, 1)
+
+	g.waitForTools()
+	hits := g.searchIndex.search(g.tools, args.Query, limit)
+
+	return structuredResult(map[string]any{"tools": hits})
+}
+
+// handleCallTool forwards a call to a tool found through search
+func (g *Gateway) handleCallTool(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Tool      string          `json:"tool"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Tool == "" {
+		return gatewayError(errCodeInvalidArgs, fmt.Sprintf("%s needs the name of the tool to call", callToolName), nil), nil
+	}
+
+	g.waitForTools()
+	return g.callRegisteredTool(ctx, req, args.Tool, args.Arguments)
+}
@@ -2,21 +2,23 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"e2b.dev/mcp-gateway/pkg/gateway/transport"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
 // SetupHTTPServer creates and configures the HTTP server with all routes
-func SetupHTTPServer(mcpServer *mcp.Server, addr string, token string) *http.Server {
+func SetupHTTPServer(g *Gateway, addr string, token string) *http.Server {
 	// Create MCP protocol handler
 	handler := mcp.NewStreamableHTTPHandler(func(req *http.Request) *mcp.Server {
-		return mcpServer
+		return g.Server()
 	}, nil)
 
 	// Create mux for multiple endpoints
@@ -28,6 +30,16 @@ func SetupHTTPServer(mcpServer *mcp.Server, addr string, token string) *http.Ser
 		w.Write([]byte("OK"))
 	})
 
+	// Image pre-pull progress, counts only since server names are protected by the status endpoint
+	mux.HandleFunc("/health/images", func(w http.ResponseWriter, r *http.Request) {
+		status := g.prePull.status()
+		status.Images = nil
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			zap.L().Error("Failed to encode pre-pull status", zap.Error(err))
+		}
+	})
+
 	// MCP protocol endpoint with auth middleware if token is provided
 	mcpHandler := http.Handler(handler)
 	if token != "" {
@@ -36,6 +48,13 @@ func SetupHTTPServer(mcpServer *mcp.Server, addr string, token string) *http.Ser
 	}
 	mux.Handle("/mcp", mcpHandler)
 
+	// Status endpoint exposes server names and errors, so it is protected like the MCP endpoint
+	statusHandler := http.Handler(statusHandler(g))
+	if token != "" {
+		statusHandler = authMiddleware(token)(statusHandler)
+	}
+	mux.Handle("/status", statusHandler)
+
 	// Add global middleware (logging and CORS, but not auth)
 	var finalHandler http.Handler = mux
 	finalHandler = loggingHandler(finalHandler)
@@ -47,6 +66,16 @@ func SetupHTTPServer(mcpServer *mcp.Server, addr string, token string) *http.Ser
 	}
 }
 
+// statusHandler serves the gateway status as JSON
+func statusHandler(g *Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(g.Status()); err != nil {
+			zap.L().Error("Failed to encode status", zap.Error(err))
+		}
+	}
+}
+
 // Run starts the HTTP server and handles graceful shutdown
 func Run(srv *http.Server, clientPool *ClientPool) {
 	sigChan := make(chan os.Signal, 1)
@@ -71,6 +100,13 @@ func Run(srv *http.Server, clientPool *ClientPool) {
 		zap.L().Error("Error closing pool", zap.Error(err))
 	}
 
+	// Remove containers that did not stop with their session
+	if runtime := clientPool.Runtime(); runtime != nil {
+		if _, err := transport.CleanupContainers(shutdownCtx, runtime, transport.CleanupInstance); err != nil {
+			zap.L().Error("Error removing containers", zap.Error(err))
+		}
+	}
+
 	// Shutdown HTTP server
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		zap.L().Error("Error shutting down HTTP server", zap.Error(err))
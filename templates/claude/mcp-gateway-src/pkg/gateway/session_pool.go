@@ -3,29 +3,109 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"e2b.dev/mcp-gateway/pkg/egress"
 	"e2b.dev/mcp-gateway/pkg/gateway/transport"
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
+// maxRestartHistory is the number of restart events kept per pool key
+const maxRestartHistory = 20
+
 // ClientPool is a thread-safe pool of MCP client sessions
 type ClientPool struct {
 	mu        sync.RWMutex
 	sessions  map[string]*mcp.ClientSession
-	longLived map[string]bool // tracks which sessions are long-lived
+	longLived map[string]*supervisedSession // tracks which sessions are long-lived
+	restarts  map[string][]RestartEvent     // restart history per pool key
+	config    GatewayConfig
+	egress    *egress.Manager    // Filtering proxies for servers with allowHosts
+	runtime   containers.Runtime // Runs the containers of Docker servers, owned by the caller
+	prePull   *prePuller         // Images pulled for the current config, nil when pre-pull is disabled
+
+	ctx    context.Context // Lifetime of the pool, cancelled by Close
+	cancel context.CancelFunc
 }
 
-// NewClientPool creates a new client pool
-func NewClientPool() *ClientPool {
+// supervisedSession holds what is needed to health-check and recreate a long-lived session
+type supervisedSession struct {
+	mcpKey  string
+	server  catalog.Server
+	session *mcp.ClientSession
+	status  string
+	since   time.Time
+}
+
+// RestartEvent records a single automatic restart of a long-lived session
+type RestartEvent struct {
+	Time     time.Time `json:"time"`
+	Reason   string    `json:"reason"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SessionStatus describes a pooled session for the status endpoint
+type SessionStatus struct {
+	Key       string         `json:"key"`
+	Server    string         `json:"server"`
+	LongLived bool           `json:"longLived"`
+	State     string         `json:"state,omitempty"`
+	Since     time.Time      `json:"since,omitzero"`
+	Restarts  []RestartEvent `json:"restarts,omitempty"`
+}
+
+// Supervised session states
+const (
+	sessionHealthy    = "healthy"
+	sessionRestarting = "restarting"
+	sessionFailed     = "failed"
+)
+
+// NewClientPool creates a new client pool running containers on the given runtime
+func NewClientPool(runtime containers.Runtime) *ClientPool {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ClientPool{
 		sessions:  make(map[string]*mcp.ClientSession),
-		longLived: make(map[string]bool),
+		longLived: make(map[string]*supervisedSession),
+		restarts:  make(map[string][]RestartEvent),
+		config:    DefaultGatewayConfig(),
+		egress:    egress.NewManager(egress.Config{}, runtime),
+		runtime:   runtime,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
+// Runtime returns the container runtime of the pool
+func (p *ClientPool) Runtime() containers.Runtime {
+	return p.runtime
+}
+
+// setPrePuller makes sessions rely on the images pulled by the pre-pull of the current config
+func (p *ClientPool) setPrePuller(prePull *prePuller) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prePull = prePull
+}
+
+// Configure replaces the liveness and retry settings used for sessions created from now on
+func (p *ClientPool) Configure(cfg GatewayConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cfg.Egress.Network != p.config.Egress.Network || cfg.Egress.ListenHost != p.config.Egress.ListenHost {
+		p.egress.Close()
+		p.egress = egress.NewManager(egress.Config{Network: cfg.Egress.Network, ListenHost: cfg.Egress.ListenHost}, p.runtime)
+	}
+	p.config = cfg
+}
+
 // Acquire gets or creates an MCP session for the given key and session ID
 func (p *ClientPool) Acquire(ctx context.Context, mcpKey string, sessionID string, cServer catalog.Server) (*mcp.ClientSession, error) {
 	key := fmt.Sprintf("%s:%s", mcpKey, sessionID)
@@ -51,23 +131,93 @@ func (p *ClientPool) Acquire(ctx context.Context, mcpKey string, sessionID strin
 	// Store in pool
 	p.mu.Lock()
 	p.sessions[key] = session
+	var supervised *supervisedSession
 	if cServer.LongLived {
-		p.longLived[key] = true
+		supervised = &supervisedSession{
+			mcpKey:  mcpKey,
+			server:  cServer,
+			session: session,
+			status:  sessionHealthy,
+			since:   time.Now(),
+		}
+		p.longLived[key] = supervised
 	}
+	liveness := p.config.Liveness
 	p.mu.Unlock()
 
+	if supervised != nil && !liveness.Disabled {
+		go p.supervise(key, supervised, liveness)
+	}
+
 	return session, nil
 }
 
-// createSession creates a new MCP session using the appropriate transport
+// createSession creates a new MCP session, retrying transient failures according to the transport's retry policy
 func (p *ClientPool) createSession(ctx context.Context, serverName string, server catalog.Server) (*mcp.ClientSession, error) {
+	p.mu.RLock()
+	policy := p.config.RetryPolicy(server.Type)
+	p.mu.RUnlock()
+
+	attempts := max(policy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := policy.Backoff.Delay(attempt - 1)
+			zap.L().Warn("Retrying session creation",
+				zap.String("component", "POOL"),
+				zap.String("server", serverName),
+				zap.Int("attempt", attempt),
+				zap.Duration("delay", delay),
+				zap.Error(lastErr))
+			if !sleepContext(ctx, delay) {
+				return nil, fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+			}
+		}
+
+		session, err := p.connect(ctx, serverName, server)
+		if err == nil {
+			return session, nil
+		}
+		lastErr = err
+
+		// Don't retry when the caller gave up
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if attempts > 1 {
+		return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, lastErr)
+	}
+	return nil, lastErr
+}
+
+// connect creates a new MCP session using the appropriate transport
+func (p *ClientPool) connect(ctx context.Context, serverName string, server catalog.Server) (*mcp.ClientSession, error) {
 	// Create MCP client
 	client := mcp.NewClient(&mcp.Implementation{
 		Name: server.Name,
 	}, nil)
 
+	p.mu.RLock()
+	opts := transport.Options{
+		Runtime:   p.runtime,
+		Resources: p.config.ResourcesFor(serverName),
+		Secrets:   p.config.SecretDeliveryFor(serverName),
+		Images:    p.config.ImagesFor(serverName),
+	}
+	if !p.config.Egress.Disabled {
+		opts.Egress = p.egress
+	}
+	// Images the pre-pull of this config pulled are not pulled again for every session
+	if opts.Images.PullPolicy == transport.PullAlways && p.prePull.pulled(server.Image) {
+		opts.Images.PullPolicy = transport.PullIfNotPresent
+	}
+	p.mu.RUnlock()
+
 	// Get appropriate transport and create session
-	t := transport.GetTransport(server.Type)
+	t := transport.GetTransport(server.Type, opts)
 	return t.CreateSession(ctx, client, server, serverName)
 }
 
@@ -80,7 +230,7 @@ func (p *ClientPool) Release(mcpKey string, sessionID string) error {
 	defer p.mu.Unlock()
 
 	// Check if this is a long-lived session
-	if p.longLived[key] {
+	if _, ok := p.longLived[key]; ok {
 		// Don't delete or close long-lived sessions
 		return nil
 	}
@@ -95,6 +245,9 @@ func (p *ClientPool) Release(mcpKey string, sessionID string) error {
 // Close closes all sessions in the pool, including long-lived ones
 // This should be called during graceful shutdown
 func (p *ClientPool) Close() error {
+	// Stop supervisors before closing so closed transports are not restarted
+	p.cancel()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -107,5 +260,222 @@ func (p *ClientPool) Close() error {
 		delete(p.longLived, key)
 	}
 
+	if err := p.egress.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+// CloseServer closes all sessions of a server, including long-lived ones
+func (p *ClientPool) CloseServer(mcpKey string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var firstErr error
+	for key, session := range p.sessions {
+		if serverFromPoolKey(key) != mcpKey {
+			continue
+		}
+		if err := session.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(p.sessions, key)
+		delete(p.longLived, key)
+	}
+
+	// Sessions being restarted are only tracked as long-lived, dropping them stops their supervisor
+	for key := range p.longLived {
+		if serverFromPoolKey(key) == mcpKey {
+			delete(p.longLived, key)
+		}
+	}
+
 	return firstErr
 }
+
+// Status returns a snapshot of the pooled sessions and the restart history of long-lived ones
+func (p *ClientPool) Status() []SessionStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]SessionStatus, 0, len(p.sessions))
+	seen := make(map[string]bool, len(p.sessions))
+	for key, s := range p.longLived {
+		seen[key] = true
+		statuses = append(statuses, SessionStatus{
+			Key:       key,
+			Server:    s.mcpKey,
+			LongLived: true,
+			State:     s.status,
+			Since:     s.since,
+			Restarts:  append([]RestartEvent(nil), p.restarts[key]...),
+		})
+	}
+	for key := range p.sessions {
+		if seen[key] {
+			continue
+		}
+		statuses = append(statuses, SessionStatus{Key: key, Server: serverFromPoolKey(key)})
+	}
+
+	return statuses
+}
+
+// supervise pings a long-lived session and recreates it when it stops answering or its transport closes
+func (p *ClientPool) supervise(key string, s *supervisedSession, cfg LivenessConfig) {
+	logger := zap.L().With(zap.String("component", "POOL"), zap.String("key", key))
+
+	for {
+		reason := p.watch(s.session, cfg)
+		if reason == "" {
+			return // Pool is closing
+		}
+
+		if !p.detach(key, s) {
+			return // Session was released or replaced in the meantime
+		}
+
+		logger.Warn("Long-lived session is unhealthy, restarting", zap.String("reason", reason))
+		s.session.Close()
+
+		session, attempts, err := p.recreate(key, s, cfg)
+		event := RestartEvent{Time: time.Now(), Reason: reason, Attempts: attempts}
+		if err != nil {
+			event.Error = err.Error()
+			p.recordRestart(key, event)
+			p.setState(s, sessionFailed)
+			logger.Error("Giving up restarting long-lived session", zap.Int("attempts", attempts), zap.Error(err))
+			return
+		}
+		p.recordRestart(key, event)
+
+		if !p.attach(key, s, session) {
+			session.Close()
+			return
+		}
+		logger.Info("Long-lived session restarted", zap.Int("attempts", attempts))
+	}
+}
+
+// watch blocks until the session needs a restart and returns the reason, or "" when the pool closes
+func (p *ClientPool) watch(session *mcp.ClientSession, cfg LivenessConfig) string {
+	closed := make(chan struct{})
+	go func() {
+		session.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(max(time.Duration(cfg.Interval), time.Second))
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-p.ctx.Done():
+			return ""
+		case <-closed:
+			return "transport closed"
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(p.ctx, max(time.Duration(cfg.Timeout), time.Second))
+			err := session.Ping(pingCtx, nil)
+			cancel()
+			if err == nil {
+				failures = 0
+				continue
+			}
+			if p.ctx.Err() != nil {
+				return ""
+			}
+
+			failures++
+			if failures >= max(cfg.FailureThreshold, 1) {
+				return fmt.Sprintf("ping failed %d times: %v", failures, err)
+			}
+		}
+	}
+}
+
+// recreate creates a replacement session, backing off between failed attempts
+func (p *ClientPool) recreate(key string, s *supervisedSession, cfg LivenessConfig) (*mcp.ClientSession, int, error) {
+	var lastErr error
+	for attempt := 1; cfg.MaxRestarts <= 0 || attempt <= cfg.MaxRestarts; attempt++ {
+		if attempt > 1 && !sleepContext(p.ctx, cfg.Backoff.Delay(attempt-1)) {
+			return nil, attempt - 1, p.ctx.Err()
+		}
+
+		session, err := p.connect(p.ctx, s.mcpKey, s.server)
+		if err == nil {
+			return session, attempt, nil
+		}
+		lastErr = err
+
+		zap.L().Warn("Failed to restart long-lived session",
+			zap.String("component", "POOL"),
+			zap.String("key", key),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+
+	return nil, cfg.MaxRestarts, lastErr
+}
+
+// detach removes an unhealthy supervised session from the pool so Acquire stops handing it out
+func (p *ClientPool) detach(key string, s *supervisedSession) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.longLived[key] != s || p.sessions[key] != s.session {
+		return false
+	}
+	delete(p.sessions, key)
+	s.status = sessionRestarting
+	s.since = time.Now()
+	return true
+}
+
+// attach stores a recreated session unless another one was created for the same key meanwhile
+func (p *ClientPool) attach(key string, s *supervisedSession, session *mcp.ClientSession) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx.Err() != nil || p.longLived[key] != s {
+		return false
+	}
+	if _, exists := p.sessions[key]; exists {
+		return false
+	}
+	p.sessions[key] = session
+	s.session = session
+	s.status = sessionHealthy
+	s.since = time.Now()
+	return true
+}
+
+// setState updates the state reported for a supervised session
+func (p *ClientPool) setState(s *supervisedSession, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.status = state
+	s.since = time.Now()
+}
+
+// recordRestart appends to the bounded restart history of a pool key
+func (p *ClientPool) recordRestart(key string, event RestartEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	history := append(p.restarts[key], event)
+	if len(history) > maxRestartHistory {
+		history = history[len(history)-maxRestartHistory:]
+	}
+	p.restarts[key] = history
+}
+
+// serverFromPoolKey strips the session ID suffix from a pool key
+func serverFromPoolKey(key string) string {
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// containerCount returns how many containers of a server the runtime runs
+func containerCount(t *testing.T, runtime containers.Runtime, serverName string) int {
+	t.Helper()
+	list, err := runtime.List(context.Background(), map[string]string{"docker-mcp-name": serverName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(list)
+}
+
+func TestClientPoolReusesSessionsPerClientSession(t *testing.T) {
+	const image = "mcp/echo:latest"
+	runtime := containers.NewFake()
+	runtime.AddImage(image, containers.FakeMCPServer(mcp.NewServer(&mcp.Implementation{Name: "echo"}, nil)), true)
+
+	pool := NewClientPool(runtime)
+	defer pool.Close()
+	server := catalog.Server{Name: "echo", Type: "server", Image: image, LongLived: true}
+
+	first, err := pool.Acquire(t.Context(), "echo", "a", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := pool.Acquire(t.Context(), "echo", "a", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Error("the same client session got a second server session")
+	}
+	if _, err := pool.Acquire(t.Context(), "echo", "b", server); err != nil {
+		t.Fatal(err)
+	}
+	if n := containerCount(t, runtime, "echo"); n != 2 {
+		t.Errorf("got %d containers, want one per client session", n)
+	}
+
+	// Closing the server stops all of its containers
+	if err := pool.CloseServer("echo"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for containerCount(t, runtime, "echo") > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("containers were not removed after CloseServer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientPoolWithoutRuntime(t *testing.T) {
+	pool := NewClientPool(nil)
+	defer pool.Close()
+	cfg := DefaultGatewayConfig()
+	cfg.Retry = map[string]RetryPolicy{"docker": {MaxAttempts: 1}}
+	pool.Configure(cfg)
+
+	_, err := pool.Acquire(t.Context(), "echo", "a", catalog.Server{Name: "echo", Type: "server", Image: "mcp/echo:latest"})
+	if err == nil || !strings.Contains(err.Error(), "no container runtime") {
+		t.Errorf("got %v, want the missing runtime reported", err)
+	}
+}
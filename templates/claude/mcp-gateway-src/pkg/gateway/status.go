@@ -0,0 +1,25 @@
+package gateway
+
+// Status is the operational snapshot served on the status endpoint
+type Status struct {
+	Sessions          []SessionStatus   `json:"sessions"`
+	FailedDiscoveries []DiscoveryStatus `json:"failedDiscoveries"`
+	Breakers          []BreakerStatus   `json:"breakers"`
+	Queues            []QueueStatus     `json:"queues"`
+	RateLimits        []RateLimitStatus `json:"rateLimits"`
+	Cache             CacheStatus       `json:"cache"`
+	PrePull           PrePullStatus     `json:"prePull"`
+}
+
+// Status collects the current state of the gateway components
+func (g *Gateway) Status() Status {
+	return Status{
+		Sessions:          g.pool.Status(),
+		FailedDiscoveries: g.discoveryStatus(),
+		Breakers:          g.breakers.status(),
+		Queues:            g.limiters.status(),
+		RateLimits:        g.rateLimiter.status(),
+		Cache:             g.cache.status(),
+		PrePull:           g.prePull.status(),
+	}
+}
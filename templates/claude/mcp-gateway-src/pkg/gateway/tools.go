@@ -2,9 +2,11 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -21,8 +23,16 @@ func (g *Gateway) dynamicallyListTools(ctx context.Context) {
 	// Extract session ID from context
 	sessionID := getSessionID(ctx)
 
+	// Servers served from the discovery cache, revalidated once tools/list is unblocked
+	cached := make(map[string]catalog.Server)
+	defer func() {
+		if len(cached) > 0 {
+			go g.revalidateServers(context.WithoutCancel(ctx), sessionID, cached)
+		}
+	}()
+
 	// Create errgroup with context and limit concurrency to numCPU * 2
-	eg, ctx := errgroup.WithContext(ctx)
+	eg, egCtx := errgroup.WithContext(ctx)
 	eg.SetLimit(runtime.NumCPU() * 2)
 
 	for configKey := range g.userConfigs {
@@ -31,7 +41,8 @@ func (g *Gateway) dynamicallyListTools(ctx context.Context) {
 			if catalogServer, ok := g.buildGitHubServer(configKey); ok {
 				serverName := configKey
 				eg.Go(func() error {
-					return discoverAndRegisterTools(ctx, g.pool, g.server, serverName, sessionID, catalogServer)
+					g.discoverServer(egCtx, serverName, sessionID, catalogServer)
+					return nil
 				})
 			}
 			continue
@@ -48,12 +59,24 @@ func (g *Gateway) dynamicallyListTools(ctx context.Context) {
 			continue
 		}
 
+		// Lazy servers only start on their first tool call
+		if g.config.LazyFor(actualServerName) && g.registerLazyServer(actualServerName, cServer) {
+			continue
+		}
+
+		// Serve tools from the discovery cache and check them against the server in the background
+		if g.registerCachedTools(actualServerName, cServer) {
+			cached[actualServerName] = cServer
+			continue
+		}
+
 		// Capture loop variables for goroutine
 		serverName := actualServerName
 		catalogServer := cServer
 
 		eg.Go(func() error {
-			return discoverAndRegisterTools(ctx, g.pool, g.server, serverName, sessionID, catalogServer)
+			g.discoverServer(egCtx, serverName, sessionID, catalogServer)
+			return nil
 		})
 	}
 
@@ -111,45 +134,238 @@ func (g *Gateway) buildGitHubServer(serverName string) (catalog.Server, bool) {
 	return catalogServer, true
 }
 
+// discoverServer discovers a server's tools and schedules a background rediscovery if that fails
+func (g *Gateway) discoverServer(ctx context.Context, serverName string, sessionID string, catalogServer catalog.Server) {
+	if err := g.discoverAndRegisterTools(ctx, serverName, sessionID, catalogServer); err != nil {
+		// Don't fail the entire operation, the server is retried in the background
+		g.markDiscoveryFailed(ctx, serverName, catalogServer, err)
+		return
+	}
+	g.clearDiscoveryFailure(serverName)
+}
+
 // discoverAndRegisterTools discovers and registers tools for a single MCP server
-func discoverAndRegisterTools(ctx context.Context, clientPool *ClientPool, server *mcp.Server, serverName string, sessionID string, catalogServer catalog.Server) error {
-	session, err := clientPool.Acquire(ctx, serverName, sessionID, catalogServer)
+func (g *Gateway) discoverAndRegisterTools(ctx context.Context, serverName string, sessionID string, catalogServer catalog.Server) error {
+	// Let a running pre-pull finish so the session's retries only cover starting the container
+	g.prePull.wait(ctx, serverName)
+
+	session, err := g.pool.Acquire(ctx, serverName, sessionID, catalogServer)
 	if err != nil {
 		zap.L().Error("Failed to acquire session", zap.String("component", "TOOLS"), zap.String("server", serverName), zap.Error(err))
-		return nil // Don't fail the entire operation
+		return fmt.Errorf("failed to acquire session: %w", err)
 	}
-	defer clientPool.Release(serverName, sessionID)
+	defer g.pool.Release(serverName, sessionID)
 
+	return g.registerSessionTools(ctx, serverName, catalogServer, session)
+}
+
+// registerSessionTools lists the tools of a connected server, registers them in place of the
+// tools registered for it so far and persists them in the discovery cache
+func (g *Gateway) registerSessionTools(ctx context.Context, serverName string, catalogServer catalog.Server, session *mcp.ClientSession) error {
 	tools, err := session.ListTools(ctx, &mcp.ListToolsParams{})
 	if err != nil {
 		zap.L().Error("Failed to list tools", zap.String("component", "TOOLS"), zap.String("server", serverName), zap.Error(err))
-		return nil // Don't fail the entire operation
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	g.registerListedTools(serverName, catalogServer, tools.Tools)
+	return nil
+}
+
+// registerListedTools registers the tools a server listed in place of the tools registered for it
+// so far and persists them in the discovery cache
+func (g *Gateway) registerListedTools(serverName string, catalogServer catalog.Server, tools []*mcp.Tool) {
+	g.discoveryCache.store(catalogServer, tools)
+
+	previous := g.tools.namesForServer(serverName)
+	toolHandler := g.createToolHandler(serverName, catalogServer)
+
+	listed := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		tool.Name = fmt.Sprintf("%s-%s", serverName, tool.Name)
+		listed[tool.Name] = true
+		g.addTool(serverName, tool, toolHandler)
+	}
+
+	// Drop stubs and cached tools the server no longer provides
+	var stale []string
+	for _, name := range previous {
+		if !listed[name] {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		g.tools.remove(stale...)
+		if g.config.ToolMode != toolModeSearch {
+			g.server.RemoveTools(stale...)
+		}
+		zap.L().Info("Removed tools the server no longer lists",
+			zap.String("component", "TOOLS"),
+			zap.String("server", serverName),
+			zap.Strings("tools", stale))
 	}
+}
 
-	toolHandler := createToolHandler(clientPool, serverName, catalogServer)
+// registerCachedTools registers the tools of a server from the discovery cache without starting it
+func (g *Gateway) registerCachedTools(serverName string, catalogServer catalog.Server) bool {
+	tools, ok := g.discoveryCache.load(catalogServer)
+	if !ok {
+		return false
+	}
 
-	for _, tool := range tools.Tools {
+	toolHandler := g.createToolHandler(serverName, catalogServer)
+	for _, tool := range tools {
 		tool.Name = fmt.Sprintf("%s-%s", serverName, tool.Name)
-		server.AddTool(tool, toolHandler)
+		g.addTool(serverName, tool, toolHandler)
 	}
 
-	return nil
+	zap.L().Info("Registered tools from discovery cache",
+		zap.String("component", "TOOLS"),
+		zap.String("server", serverName),
+		zap.Int("tools", len(tools)))
+	return true
+}
+
+// revalidateServers rediscovers servers whose tools were served from the discovery cache
+func (g *Gateway) revalidateServers(ctx context.Context, sessionID string, servers map[string]catalog.Server) {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU() * 2)
+
+	for serverName, catalogServer := range servers {
+		eg.Go(func() error {
+			g.discoverServer(ctx, serverName, sessionID, catalogServer)
+			return nil
+		})
+	}
+	eg.Wait()
 }
 
 // createToolHandler creates a handler function for tool calls
-func createToolHandler(clientPool *ClientPool, serverName string, catalogServer catalog.Server) func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (g *Gateway) createToolHandler(serverName string, catalogServer catalog.Server) func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, params *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		sessionID := getSessionID(ctx)
+		registered, registeredOK := g.tools.get(params.Params.Name)
+
+		// Reject malformed calls before a backend is started for them
+		if registeredOK && registered.input != nil && !g.config.Validation.DisableInput {
+			arguments, err := validateArguments(registered.input, params.Params.Arguments, !g.config.Validation.DisableDefaults)
+			if err != nil {
+				return gatewayError(errCodeInvalidArgs,
+					fmt.Sprintf("Invalid arguments for %s: %v", params.Params.Name, err),
+					map[string]any{"server": serverName, "tool": params.Params.Name}), nil
+			}
+			params.Params.Arguments = arguments
+		}
+
+		// Serve read-only and idempotent tools from the cache
+		ttl, cacheEnabled := g.config.CacheTTLFor(serverName)
+		if !registeredOK || !cacheable(registered.tool) {
+			cacheEnabled = false
+		}
 
-		session, err := clientPool.Acquire(ctx, serverName, sessionID, catalogServer)
+		var key string
+		if cacheEnabled {
+			key = cacheKey(serverName, params.Params.Name, params.Params.Arguments)
+			if !cacheBypassed(params.Params) {
+				if result, ok := g.cache.get(serverName, key); ok {
+					setGatewayMeta(result, "cache", "hit")
+					return g.limitResult(ctx, params.Session, serverName, params.Params.Name, result), nil
+				}
+			}
+		}
+
+		result, err := g.callServer(ctx, serverName, catalogServer, params)
+
+		// Typed pipelines rely on structuredContent matching the declared outputSchema
+		if err == nil && result != nil && !result.IsError && g.config.Validation.Output && registeredOK && registered.output != nil {
+			if verr := validateStructuredContent(registered.output, result); verr != nil {
+				return gatewayError(errCodeInvalidOut,
+					fmt.Sprintf("Result of %s does not match its outputSchema: %v", params.Params.Name, verr),
+					map[string]any{"server": serverName, "tool": params.Params.Name}), nil
+			}
+		}
+
+		if cacheEnabled && err == nil && result != nil && !result.IsError {
+			g.cache.put(serverName, key, result, ttl)
+			setGatewayMeta(result, "cache", "miss")
+		}
 		if err != nil {
-			return &mcp.CallToolResult{}, fmt.Errorf("failed to acquire session: %w", err)
+			return result, err
+		}
+		return g.limitResult(ctx, params.Session, serverName, params.Params.Name, result), nil
+	}
+}
+
+// callServer applies rate limits, concurrency limits and the circuit breaker before forwarding a call
+func (g *Gateway) callServer(ctx context.Context, serverName string, catalogServer catalog.Server, params *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Reject calls above the configured rates before they cost anything
+	if rejection := g.rateLimiter.allow(g.rateLimiter.checks(serverName, params, getSessionID(ctx))); rejection != nil {
+		return gatewayError(errCodeRateLimited,
+			fmt.Sprintf("Rate limit exceeded for %s (%g requests per %s). Retry in %s.",
+				rejection.scope, rejection.limit.Requests, rejection.limit.interval(), rejection.retryAfter.Round(time.Millisecond)),
+			map[string]any{
+				"server":            serverName,
+				"scope":             rejection.scope,
+				"retryAfterSeconds": rejection.retryAfter.Seconds(),
+			}), nil
+	}
+
+	// Wait for a free slot on servers with limited concurrency
+	if limiter := g.limiters.get(serverName); limiter != nil {
+		release, err := limiter.acquire(ctx)
+		switch {
+		case errors.Is(err, errQueueFull):
+			return gatewayError(errCodeQueueFull,
+				fmt.Sprintf("MCP server %q is busy: %d calls are already queued. Retry later.", serverName, limiter.cfg.QueueSize),
+				map[string]any{"server": serverName}), nil
+		case errors.Is(err, errQueueTimeout):
+			return gatewayError(errCodeQueueTimeout,
+				fmt.Sprintf("MCP server %q is busy: no slot became free within %s. Retry later.", serverName, time.Duration(limiter.cfg.QueueTimeout)),
+				map[string]any{"server": serverName}), nil
+		case err != nil:
+			return &mcp.CallToolResult{}, err
 		}
-		defer clientPool.Release(serverName, sessionID)
+		defer release()
+	}
 
-		return session.CallTool(ctx, &mcp.CallToolParams{
-			Arguments: params.Params.Arguments,
-			Name:      strings.TrimPrefix(params.Params.Name, serverName+"-"),
-		})
+	if g.config.Breaker.Disabled {
+		return g.callBackend(ctx, serverName, catalogServer, params)
+	}
+
+	// Fail fast while the server keeps failing
+	breaker := g.breakers.get(serverName)
+	if ok, retryAfter := breaker.allow(); !ok {
+		status := breaker.status(serverName)
+		return gatewayError(errCodeCircuitOpen,
+			fmt.Sprintf("MCP server %q is temporarily unavailable after repeated failures (last error: %s). Retry in %s.",
+				serverName, status.LastError, retryAfter.Round(time.Second)),
+			map[string]any{
+				"server":            serverName,
+				"retryAfterSeconds": retryAfter.Seconds(),
+			}), nil
 	}
+
+	result, err := g.callBackend(ctx, serverName, catalogServer, params)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, which says nothing about the server's health
+		breaker.abandon()
+	} else {
+		breaker.record(err)
+	}
+	return result, err
+}
+
+// callBackend forwards a tool call to the server's pooled session
+func (g *Gateway) callBackend(ctx context.Context, serverName string, catalogServer catalog.Server, params *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := getSessionID(ctx)
+
+	session, err := g.pool.Acquire(ctx, serverName, sessionID, catalogServer)
+	if err != nil {
+		return &mcp.CallToolResult{}, fmt.Errorf("failed to acquire session: %w", err)
+	}
+	defer g.pool.Release(serverName, sessionID)
+
+	return session.CallTool(ctx, &mcp.CallToolParams{
+		Arguments: params.Params.Arguments,
+		Name:      strings.TrimPrefix(params.Params.Name, serverName+"-"),
+	})
 }
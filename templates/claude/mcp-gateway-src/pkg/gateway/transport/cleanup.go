@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Labels identifying the gateway process that owns a container
+const (
+	labelInstance = "e2b.dev/mcp-gateway-instance" // Random ID of the gateway process
+	labelHost     = "e2b.dev/mcp-gateway-host"     // Machine the gateway runs on
+	labelBoot     = "e2b.dev/mcp-gateway-boot"     // Boot of that machine, empty when unknown
+	labelLock     = "e2b.dev/mcp-gateway-lock"     // Set when the gateway holds its instance lock
+)
+
+// instanceID identifies this gateway process in container labels
+var instanceID = uuid.NewString()
+
+// instancesDir holds a lock file per gateway process, locked for as long as the process runs.
+// The lock is released by the kernel when the process dies, unlike a pid that may be reused.
+var instancesDir = filepath.Join(os.TempDir(), "mcp-gateway-instances")
+
+var (
+	instanceLockOnce sync.Once
+	instanceLock     *os.File // Open for the lifetime of the process, nil when locking failed
+)
+
+// CleanupScope selects which gateway containers CleanupContainers removes
+type CleanupScope int
+
+const (
+	// CleanupStale removes containers of gateway processes on this machine that are no longer running
+	CleanupStale CleanupScope = iota
+	// CleanupInstance removes the containers of this process, used on shutdown
+	CleanupInstance
+	// CleanupAll removes every gateway container, including ones of running gateways
+	CleanupAll
+)
+
+// instanceLabels returns the labels tying a container to this gateway process
+func instanceLabels() map[string]string {
+	instanceLockOnce.Do(func() {
+		lock, err := lockInstance()
+		if err != nil {
+			zap.L().Warn("Failed to lock gateway instance, its containers are only removed on shutdown or with --all",
+				zap.String("component", "DOCKER"),
+				zap.Error(err))
+			return
+		}
+		instanceLock = lock
+	})
+
+	labels := map[string]string{
+		labelInstance: instanceID,
+		labelHost:     hostID(),
+		labelBoot:     bootID(),
+	}
+	if instanceLock != nil {
+		labels[labelLock] = "true"
+	}
+	return labels
+}
+
+// lockInstance creates the lock file of this process and takes an exclusive lock on it
+func lockInstance() (*os.File, error) {
+	if err := os.MkdirAll(instancesDir, 0o755); err != nil {
+		return nil, err
+	}
+	// Gateways of every user share the directory, like the temporary directory it lives in
+	os.Chmod(instancesDir, 0o1777)
+
+	file, err := os.OpenFile(instanceLockPath(instanceID), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// instanceLockPath returns the lock file of a gateway process
+func instanceLockPath(id string) string {
+	return filepath.Join(instancesDir, id+".lock")
+}
+
+// hostID identifies this machine, preferring the machine ID over the host name
+func hostID() string {
+	if id, err := os.ReadFile("/etc/machine-id"); err == nil && len(strings.TrimSpace(string(id))) > 0 {
+		return strings.TrimSpace(string(id))
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// bootID identifies the current boot of this machine, empty when the system doesn't expose it
+func bootID() string {
+	id, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(id))
+}
+
+// CleanupContainers removes gateway containers in the given scope and returns how many were removed.
+// Egress networks left without containers are removed as well, whichever gateway created them.
+func CleanupContainers(ctx context.Context, runtime containers.Runtime, scope CleanupScope) (int, error) {
+	labels := map[string]string{"docker-mcp": "true", labelInstance: ""}
+	if scope == CleanupInstance {
+		labels[labelInstance] = instanceID
+	}
+
+	list, err := runtime.List(ctx, labels)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	var errs []error
+	for _, c := range list {
+		if scope == CleanupStale && ownerRunning(c.Labels) {
+			continue
+		}
+
+		if err := runtime.Remove(ctx, c.ID); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", shortID(c.ID), err))
+			continue
+		}
+		removed++
+
+		zap.L().Info("Removed orphaned container",
+			zap.String("component", "DOCKER"),
+			zap.String("container", shortID(c.ID)),
+			zap.String("server", c.Labels["docker-mcp-name"]),
+			zap.String("state", c.State))
+	}
+
+	networks, err := runtime.PruneNetworks(ctx, map[string]string{"docker-mcp": "true"})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range networks {
+		zap.L().Info("Removed unused network",
+			zap.String("component", "DOCKER"),
+			zap.String("network", name))
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// ownerRunning reports whether the gateway process that created a container may still be alive.
+// Containers of other machines sharing the runtime are always considered alive.
+func ownerRunning(labels map[string]string) bool {
+	id := labels[labelInstance]
+	if id == instanceID {
+		return true
+	}
+	if labels[labelHost] != hostID() {
+		return true
+	}
+
+	// The machine rebooted since the container was created, so its gateway is gone
+	if boot := bootID(); boot != "" && labels[labelBoot] != "" && labels[labelBoot] != boot {
+		return false
+	}
+
+	// Without a lock there's no way to tell, leave the container to its gateway or --all
+	if labels[labelLock] != "true" {
+		return true
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return true
+	}
+	return instanceLockHeld(id)
+}
+
+// instanceLockHeld reports whether the lock file of a gateway process is still locked.
+// A missing or unlocked file means the process exited, its lock file is removed then.
+func instanceLockHeld(id string) bool {
+	path := instanceLockPath(id)
+	file, err := os.Open(path)
+	if err != nil {
+		return !errors.Is(err, os.ErrNotExist)
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		// Locked by its process, or unknown: either way the container is left alone
+		return true
+	}
+	os.Remove(path)
+	return false
+}
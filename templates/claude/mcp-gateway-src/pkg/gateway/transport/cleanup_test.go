@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/docker/docker/api/types/container"
+	"github.com/google/uuid"
+)
+
+func TestCleanupStaleKeepsContainersOfLiveGateways(t *testing.T) {
+	instancesDir = t.TempDir()
+
+	runtime := containers.NewFake()
+	runtime.AddImage("idle", func(ctx context.Context, stdin io.Reader, stdout io.Writer) int {
+		<-ctx.Done()
+		return 0
+	}, true)
+
+	run := func(name string, labels map[string]string) {
+		t.Helper()
+		all := map[string]string{"docker-mcp": "true", "docker-mcp-name": name}
+		maps.Copy(all, labels)
+		if _, err := runtime.Run(t.Context(), containers.Spec{Name: name, Config: &container.Config{Image: "idle", Labels: all}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gateway := func(id string) map[string]string {
+		return map[string]string{labelInstance: id, labelHost: hostID(), labelBoot: bootID(), labelLock: "true"}
+	}
+
+	// A live gateway holds the lock of its instance
+	live := uuid.NewString()
+	lock, err := os.Create(instanceLockPath(live))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crashed gateway left an unlocked lock file behind
+	crashed := uuid.NewString()
+	if err := os.WriteFile(instanceLockPath(crashed), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run("own", instanceLabels())
+	run("live", gateway(live))
+	run("crashed", gateway(crashed))
+	run("exited", gateway(uuid.NewString()))
+	otherHost := gateway(uuid.NewString())
+	otherHost[labelHost] = "another-machine"
+	run("other-host", otherHost)
+	unlocked := gateway(uuid.NewString())
+	delete(unlocked, labelLock)
+	run("unlocked", unlocked)
+	if bootID() != "" {
+		rebooted := gateway(live)
+		rebooted[labelBoot] = uuid.NewString()
+		run("rebooted", rebooted)
+	}
+
+	removed, err := CleanupContainers(t.Context(), runtime, CleanupStale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := runtime.List(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, c := range list {
+		kept = append(kept, c.Name)
+	}
+	slices.Sort(kept)
+	if want := []string{"live", "other-host", "own", "unlocked"}; !slices.Equal(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	want := 2 // crashed and exited
+	if bootID() != "" {
+		want++
+	}
+	if removed != want {
+		t.Errorf("removed %d containers, want %d", removed, want)
+	}
+	if _, err := os.Stat(instanceLockPath(crashed)); !os.IsNotExist(err) {
+		t.Errorf("lock file of the crashed gateway was not removed: %v", err)
+	}
+}
+
+func TestCleanupRemovesUnusedNetworks(t *testing.T) {
+	runtime := containers.NewFake()
+	runtime.AddImage("idle", func(ctx context.Context, stdin io.Reader, stdout io.Writer) int {
+		<-ctx.Done()
+		return 0
+	}, true)
+	for _, name := range []string{"mcp-gateway-egress-brave", "mcp-gateway-egress-fetch"} {
+		if _, err := runtime.EnsureNetwork(t.Context(), name, map[string]string{"docker-mcp": "true"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runtime.EnsureNetwork(t.Context(), "bridge-of-someone-else", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A container of another gateway still uses the network of fetch
+	spec := containers.Spec{
+		Config:     &container.Config{Image: "idle", Labels: map[string]string{"docker-mcp": "true", labelInstance: "other", labelHost: "other"}},
+		HostConfig: &container.HostConfig{NetworkMode: "mcp-gateway-egress-fetch"},
+	}
+	if _, err := runtime.Run(t.Context(), spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CleanupContainers(t.Context(), runtime, CleanupStale); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := runtime.Networks(), []string{"bridge-of-someone-else", "mcp-gateway-egress-fetch"}; !slices.Equal(got, want) {
+		t.Errorf("networks after cleanup: got %v, want %v", got, want)
+	}
+}
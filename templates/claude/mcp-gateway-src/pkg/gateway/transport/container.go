@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// stopTimeout is how long a container may take to exit after its stdin closed before it is stopped
+const stopTimeout = 5 * time.Second
+
+// invalidNameChars are characters Docker doesn't accept in container names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// runningContainer is a server container attached through the container runtime
+type runningContainer struct {
+	runtime    containers.Runtime
+	id         string
+	serverName string
+
+	exited   chan struct{} // Closed once the container stopped
+	status   containers.ExitStatus
+	stopOnce sync.Once
+}
+
+// containerName returns a unique, valid container name for a server
+func containerName(serverName string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(serverName, "-"), "-.")
+	return fmt.Sprintf("mcp-%s-%s", name, uuid.NewString()[:8])
+}
+
+// shortID abbreviates a container ID for logs
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// runContainer runs a container attached to its stdio and returns the MCP connection to it.
+// onExit runs once the container stopped and was removed.
+func runContainer(ctx context.Context, runtime containers.Runtime, serverName string, spec containers.Spec, onExit func()) (*runningContainer, *stdioConn, error) {
+	attached, err := runtime.Run(ctx, spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &runningContainer{
+		runtime:    runtime,
+		id:         attached.ID,
+		serverName: serverName,
+		exited:     make(chan struct{}),
+	}
+
+	go func() {
+		c.status = <-attached.Exit
+		close(c.exited)
+
+		logger := zap.L().With(
+			zap.String("component", "DOCKER"),
+			zap.String("runtime", runtime.Name()),
+			zap.String("server", c.serverName),
+			zap.String("container", shortID(c.id)),
+			zap.Int64("exitCode", c.status.Code),
+			zap.Bool("oomKilled", c.status.OOMKilled))
+		if c.status.Code != 0 || c.status.OOMKilled {
+			logger.Warn("Container exited")
+		} else {
+			logger.Info("Container exited")
+		}
+
+		attached.Detach()
+		c.remove()
+		if onExit != nil {
+			onExit()
+		}
+	}()
+
+	conn := newStdioConn(attached.Stdin, attached.Stdout, func() error {
+		// Closing stdin asks the server to exit, stop the container if it doesn't
+		attached.Stdin.Close()
+		go c.stop()
+		return nil
+	}, c.exitError)
+
+	return c, conn, nil
+}
+
+// stop stops the container unless it exits on its own within stopTimeout
+func (c *runningContainer) stop() {
+	c.stopOnce.Do(func() {
+		select {
+		case <-c.exited:
+			return
+		case <-time.After(stopTimeout):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout*3)
+		defer cancel()
+		if err := c.runtime.Stop(ctx, c.id, stopTimeout); err != nil {
+			zap.L().Warn("Failed to stop container",
+				zap.String("component", "DOCKER"),
+				zap.String("server", c.serverName),
+				zap.String("container", shortID(c.id)),
+				zap.Error(err))
+		}
+	})
+}
+
+// kill stops the container right away
+func (c *runningContainer) kill() {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	c.runtime.Stop(ctx, c.id, 0)
+}
+
+// remove deletes the container
+func (c *runningContainer) remove() {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout*3)
+	defer cancel()
+	if err := c.runtime.Remove(ctx, c.id); err != nil {
+		zap.L().Warn("Failed to remove container",
+			zap.String("component", "DOCKER"),
+			zap.String("server", c.serverName),
+			zap.String("container", shortID(c.id)),
+			zap.Error(err))
+	}
+}
+
+// exitError describes why the container's stdout closed, waiting briefly for the exit status
+func (c *runningContainer) exitError() error {
+	select {
+	case <-c.exited:
+	case <-time.After(2 * time.Second):
+		return nil
+	}
+
+	switch {
+	case c.status.OOMKilled:
+		return fmt.Errorf("container of %s was killed because it ran out of memory (exit code %d)", c.serverName, c.status.Code)
+	case c.status.Err != nil:
+		return fmt.Errorf("container of %s exited: %w", c.serverName, c.status.Err)
+	case c.status.Code != 0:
+		return fmt.Errorf("container of %s exited with code %d", c.serverName, c.status.Code)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+)
+
+// fakeContainerID is the ID of the only container the fake engine runs
+const fakeContainerID = "0123456789abcdef0123456789abcdef"
+
+// fakeEngine serves the parts of the Docker Engine API the runtime uses for one container.
+// The container echoes every line of its stdin to stdout and exits once stdin closes,
+// or right after it started with exitOnStart.
+type fakeEngine struct {
+	exitCode    int64
+	oomKilled   bool
+	exitOnStart bool
+
+	mu       sync.Mutex
+	conn     net.Conn // Hijacked attach connection
+	started  chan struct{}
+	exited   chan struct{}
+	removed  chan struct{}
+	exitOnce sync.Once
+}
+
+func newFakeEngine(t *testing.T, exitCode int64, oomKilled, exitOnStart bool) (*fakeEngine, containers.Runtime) {
+	t.Helper()
+	e := &fakeEngine{
+		exitCode:    exitCode,
+		oomKilled:   oomKilled,
+		exitOnStart: exitOnStart,
+		started:     make(chan struct{}),
+		exited:      make(chan struct{}),
+		removed:     make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{version}/containers/create", e.create)
+	mux.HandleFunc("POST /{version}/containers/{id}/attach", e.attach)
+	mux.HandleFunc("POST /{version}/containers/{id}/wait", e.wait)
+	mux.HandleFunc("POST /{version}/containers/{id}/start", e.start)
+	mux.HandleFunc("POST /{version}/containers/{id}/stop", e.stop)
+	mux.HandleFunc("GET /{version}/containers/{id}/json", e.inspect)
+	mux.HandleFunc("DELETE /{version}/containers/{id}", e.remove)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	engine, err := client.NewClientWithOpts(client.WithHost("tcp://"+srv.Listener.Addr().String()), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime := containers.NewDockerWithClient(engine)
+	t.Cleanup(func() { runtime.Close() })
+	return e, runtime
+}
+
+func (e *fakeEngine) create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(container.CreateResponse{ID: fakeContainerID})
+}
+
+func (e *fakeEngine) attach(w http.ResponseWriter, r *http.Request) {
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	buffered.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buffered.Flush()
+
+	e.mu.Lock()
+	e.conn = conn
+	e.mu.Unlock()
+
+	go func() {
+		<-e.started
+		if e.exitOnStart {
+			e.exit()
+			return
+		}
+		stdout := stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+		reader := bufio.NewReader(buffered)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				stdout.Write(line)
+			}
+			if err != nil {
+				e.exit()
+				return
+			}
+		}
+	}()
+}
+
+// exit stops the container, closing its output like a finished process
+func (e *fakeEngine) exit() {
+	e.exitOnce.Do(func() {
+		e.mu.Lock()
+		if e.conn != nil {
+			e.conn.Close()
+		}
+		e.mu.Unlock()
+		close(e.exited)
+	})
+}
+
+func (e *fakeEngine) wait(w http.ResponseWriter, r *http.Request) {
+	// The engine sends the headers right away and the body once the container exited
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	http.NewResponseController(w).Flush()
+
+	select {
+	case <-e.exited:
+		json.NewEncoder(w).Encode(container.WaitResponse{StatusCode: e.exitCode})
+	case <-r.Context().Done():
+	}
+}
+
+func (e *fakeEngine) start(w http.ResponseWriter, r *http.Request) {
+	close(e.started)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *fakeEngine) stop(w http.ResponseWriter, r *http.Request) {
+	e.exit()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *fakeEngine) inspect(w http.ResponseWriter, r *http.Request) {
+	status := container.StateRunning
+	select {
+	case <-e.exited:
+		status = container.StateExited
+	default:
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID: fakeContainerID,
+			State: &container.State{
+				Status:    status,
+				ExitCode:  int(e.exitCode),
+				OOMKilled: e.oomKilled,
+			},
+		},
+		Config: &container.Config{},
+	})
+}
+
+func (e *fakeEngine) remove(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("force") != "1" {
+		http.Error(w, "container is running", http.StatusConflict)
+		return
+	}
+	e.exit()
+	close(e.removed)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runFakeContainer runs the container of the fake engine through the transport
+func runFakeContainer(t *testing.T, runtime containers.Runtime) (*runningContainer, *stdioConn, chan struct{}) {
+	t.Helper()
+	spec := containers.Spec{
+		Name:       containerName("echo"),
+		Config:     &container.Config{Image: "echo", OpenStdin: true, StdinOnce: true},
+		HostConfig: &container.HostConfig{},
+	}
+
+	onExit := make(chan struct{})
+	running, conn, err := runContainer(t.Context(), runtime, "echo", spec, func() { close(onExit) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return running, conn, onExit
+}
+
+// waitClosed fails the test unless ch is closed within a few seconds
+func waitClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestRunContainerAttachesStdio(t *testing.T) {
+	engine, runtime := newFakeEngine(t, 0, false, false)
+	running, conn, onExit := runFakeContainer(t, runtime)
+	if running.id != fakeContainerID {
+		t.Errorf("container ID: got %s, want %s", running.id, fakeContainerID)
+	}
+
+	request := &jsonrpc.Request{Method: "ping"}
+	if err := conn.Write(t.Context(), request); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if echoed, ok := msg.(*jsonrpc.Request); !ok || echoed.Method != "ping" {
+		t.Errorf("got %#v, want the ping request echoed", msg)
+	}
+
+	// Closing the connection closes stdin, the container exits and is removed
+	conn.Close()
+	waitClosed(t, engine.exited, "the container to exit")
+	waitClosed(t, engine.removed, "the container to be removed")
+	waitClosed(t, onExit, "onExit")
+	if err := running.exitError(); err != nil {
+		t.Errorf("clean exit reported as %v", err)
+	}
+}
+
+func TestRunContainerReportsExit(t *testing.T) {
+	tests := []struct {
+		name      string
+		exitCode  int64
+		oomKilled bool
+		want      string
+	}{
+		{"exit code", 3, false, "exited with code 3"},
+		{"out of memory", 137, true, "ran out of memory (exit code 137)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, runtime := newFakeEngine(t, tt.exitCode, tt.oomKilled, true)
+			running, conn, onExit := runFakeContainer(t, runtime)
+			defer conn.Close()
+
+			// Stdout closes when the container exits, the read explains why
+			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+			_, err := conn.Read(ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("read error: got %v, want it to contain %q", err, tt.want)
+			}
+			if err == io.EOF {
+				t.Error("exit status was not reported")
+			}
+
+			waitClosed(t, engine.removed, "the container to be removed")
+			waitClosed(t, onExit, "onExit")
+			if running.status.Code != tt.exitCode || running.status.OOMKilled != tt.oomKilled {
+				t.Errorf("status: got %+v", running.status)
+			}
+		})
+	}
+}
@@ -3,50 +3,105 @@ package transport
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"maps"
 
-	"e2b.dev/mcp-gateway/pkg/utils"
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
-// DockerTransport creates MCP sessions by running Docker containers
-type DockerTransport struct{}
+// DockerTransport creates MCP sessions by running server images on a container runtime
+type DockerTransport struct {
+	Runtime   containers.Runtime // Docker unless configured otherwise
+	Resources Resources          // Limits applied to the container
+	Egress    Egress             // Filtering proxy for servers with allowHosts, nil leaves egress unrestricted
+	Secrets   string             // Secret delivery mode, SecretsEnv when empty
+	Images    Images             // Pull policy, mirrors and registry credentials
+}
+
+// egressEndpoint is where a container with restricted egress is attached
+type egressEndpoint struct {
+	network  string
+	proxyURL string
+}
 
 // CreateSession creates an MCP session by starting a Docker container
 func (t *DockerTransport) CreateSession(ctx context.Context, client *mcp.Client, server catalog.Server, serverName string) (*mcp.ClientSession, error) {
-	dockerClient, err := utils.NewDockerClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	if t.Runtime == nil {
+		return nil, fmt.Errorf("no container runtime to run %s", serverName)
 	}
-	defer dockerClient.Close()
 
-	// Pull the Docker image
-	if err := utils.PullImage(ctx, server.Image, dockerClient); err != nil {
+	// Make the image available according to the pull policy
+	image, err := EnsureImage(ctx, t.Runtime, server.Image, t.Images)
+	if err != nil {
 		zap.L().Error("Failed to pull image",
 			zap.String("component", "DOCKER"),
 			zap.String("image", server.Image),
 			zap.Error(err))
 		return nil, fmt.Errorf("failed to pull image %s: %w", server.Image, err)
 	}
+	server.Image = image
+
+	// Route servers that declare allowHosts through a filtering proxy on an internal network
+	var egress *egressEndpoint
+	if !server.DisableNetwork && len(server.AllowHosts) > 0 && t.Egress != nil {
+		network, proxyURL, err := t.Egress.Endpoint(ctx, serverName, server.AllowHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up egress proxy for %s: %w", serverName, err)
+		}
+		egress = &egressEndpoint{network: network, proxyURL: proxyURL}
+	}
+
+	var proxyEnv []catalog.Env
+	if egress != nil {
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			proxyEnv = append(proxyEnv, catalog.Env{Name: name, Value: egress.proxyURL})
+		}
+	}
+
+	// Secret files are bind-mounted from the gateway's filesystem, which a remote daemon can't see
+	if t.Secrets == SecretsFiles && len(server.Secrets) > 0 && !t.Runtime.Local() {
+		return nil, fmt.Errorf("secret delivery %q for %s needs a container runtime on this host", SecretsFiles, serverName)
+	}
+	env, err := prepareEnv(server, proxyEnv, t.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare environment for %s: %w", serverName, err)
+	}
 
-	// Build docker command arguments
-	args := buildDockerArgs(server, serverName)
+	spec, err := buildContainerSpec(server, serverName, t.Resources, egress, env)
+	if err != nil {
+		env.cleanup()
+		return nil, err
+	}
+
+	running, conn, err := runContainer(ctx, t.Runtime, serverName, spec, env.cleanup)
+	if err != nil {
+		env.cleanup()
+		zap.L().Error("Failed to start container",
+			zap.String("component", "DOCKER"),
+			zap.String("server", serverName),
+			zap.Error(err))
+		return nil, err
+	}
 
-	// Use context.Background() for long-lived sessions
-	commandCtx := ctx
-	if server.LongLived {
-		commandCtx = context.Background()
+	// Short-lived containers don't outlive the request that started them
+	if !server.LongLived {
+		stop := context.AfterFunc(ctx, running.kill)
+		go func() {
+			<-running.exited
+			stop()
+		}()
 	}
 
 	// Connect to Docker container
-	session, err := client.Connect(ctx, &mcp.CommandTransport{
-		Command: exec.CommandContext(commandCtx, "docker", args...),
-	}, nil)
+	session, err := client.Connect(ctx, &connTransport{conn: conn}, nil)
 	if err != nil {
+		conn.Close()
 		zap.L().Error("Failed to connect to Docker container",
 			zap.String("component", "DOCKER"),
+			zap.String("server", serverName),
 			zap.Error(err))
 		return nil, fmt.Errorf("failed to connect to Docker container: %w", err)
 	}
@@ -54,51 +109,52 @@ func (t *DockerTransport) CreateSession(ctx context.Context, client *mcp.Client,
 	return session, nil
 }
 
-// buildDockerArgs constructs the docker run command arguments
-func buildDockerArgs(server catalog.Server, serverName string) []string {
-	args := []string{"run"}
-
-	// Base security and resource settings
-	args = append(args, "--rm", "-i", "--init", "--security-opt", "no-new-privileges")
-	args = append(args, "--cpus", "1")
-	args = append(args, "--memory", "1g")
-	args = append(args, "--pull", "never")
+// buildContainerSpec constructs the container configuration of a server
+func buildContainerSpec(server catalog.Server, serverName string, resources Resources, egress *egressEndpoint, env *containerEnv) (containers.Spec, error) {
+	useInit := true
+	hostConfig := &container.HostConfig{
+		Init:        &useInit,
+		SecurityOpt: []string{"no-new-privileges"},
+		Binds:       env.binds,
+	}
 
-	// Docker MCP labels
-	args = append(args,
-		"-l", "docker-mcp=true",
-		"-l", "docker-mcp-tool-type=mcp",
-		"-l", "docker-mcp-name="+serverName,
-		"-l", "docker-mcp-transport=stdio",
-	)
+	// Base resource settings
+	if err := resources.apply(hostConfig); err != nil {
+		return containers.Spec{}, fmt.Errorf("invalid resources for %s: %w", serverName, err)
+	}
 
 	// Network isolation
 	if server.DisableNetwork {
-		args = append(args, "--network", "none")
+		hostConfig.NetworkMode = "none"
+	} else if egress != nil {
+		hostConfig.NetworkMode = container.NetworkMode(egress.network)
 	}
 
 	// Volumes from catalog (already evaluated with placeholders)
 	for _, volume := range server.Volumes {
 		if volume != "" {
-			args = append(args, "-v", volume)
+			hostConfig.Binds = append(hostConfig.Binds, volume)
 		}
 	}
 
-	// User from catalog
-	if server.User != "" {
-		args = append(args, "-u", server.User)
+	config := &container.Config{
+		Image:        server.Image,
+		Cmd:          server.Command,
+		User:         server.User, // User from catalog
+		Env:          env.env,     // Sent in the create request, never on a command line
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		OpenStdin:    true,
+		StdinOnce:    true,
+		Labels: map[string]string{
+			"docker-mcp":           "true",
+			"docker-mcp-tool-type": "mcp",
+			"docker-mcp-name":      serverName,
+			"docker-mcp-transport": "stdio",
+		},
 	}
+	maps.Copy(config.Labels, instanceLabels())
 
-	// Environment variables from catalog (already merged with user config)
-	for _, e := range server.Env {
-		if e.Name != "" && e.Value != "" {
-			args = append(args, "-e", fmt.Sprintf("%s=%s", e.Name, e.Value))
-		}
-	}
-
-	// Image and command
-	args = append(args, server.Image)
-	args = append(args, server.Command...)
-
-	return args
+	return containers.Spec{Name: containerName(serverName), Config: config, HostConfig: hostConfig}, nil
 }
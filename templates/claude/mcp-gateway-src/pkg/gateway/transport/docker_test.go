@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/docker/mcp-gateway/pkg/catalog"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// echoImage is a fake image serving an MCP server with an echo tool
+const echoImage = "mcp/echo:latest"
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+func newEchoRuntime(present bool) *containers.Fake {
+	server := mcp.NewServer(&mcp.Implementation{Name: "echo"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo"}, func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Text}}}, nil, nil
+	})
+
+	runtime := containers.NewFake()
+	runtime.AddImage(echoImage, containers.FakeMCPServer(server), present)
+	return runtime
+}
+
+// waitRemoved fails the test unless every container of the runtime is removed within a few seconds
+func waitRemoved(t *testing.T, runtime containers.Runtime) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		list, err := runtime.List(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d containers were not removed", len(list))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDockerTransportRunsServerContainer(t *testing.T) {
+	runtime := newEchoRuntime(false)
+	transport := &DockerTransport{Runtime: runtime}
+	server := catalog.Server{Type: "server", Image: echoImage, LongLived: true}
+
+	session, err := transport.CreateSession(t.Context(), mcp.NewClient(&mcp.Implementation{Name: "test"}, nil), server, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "hello"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := result.Content[0].(*mcp.TextContent); !ok || text.Text != "hello" {
+		t.Errorf("got %#v, want the text echoed", result.Content[0])
+	}
+
+	// The missing image was pulled, the container carries the labels cleanup relies on
+	if pulls := runtime.Pulls(); len(pulls) != 1 || pulls[0] != echoImage {
+		t.Errorf("pulls: got %v, want %s", pulls, echoImage)
+	}
+	list, err := runtime.List(t.Context(), map[string]string{"docker-mcp-name": "echo", labelInstance: instanceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d containers of the server, want 1", len(list))
+	}
+
+	// Closing the session closes stdin, the server exits and its container is removed
+	session.Close()
+	waitRemoved(t, runtime)
+}
+
+func TestDockerTransportStopsShortLivedContainersWithTheRequest(t *testing.T) {
+	runtime := newEchoRuntime(true)
+	transport := &DockerTransport{Runtime: runtime}
+	server := catalog.Server{Type: "server", Image: echoImage}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	session, err := transport.CreateSession(ctx, mcp.NewClient(&mcp.Implementation{Name: "test"}, nil), server, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	if len(runtime.Pulls()) != 0 {
+		t.Errorf("present image was pulled: %v", runtime.Pulls())
+	}
+
+	cancel()
+	waitRemoved(t, runtime)
+}
+
+func TestDockerTransportHonorsPullPolicyNever(t *testing.T) {
+	transport := &DockerTransport{Runtime: newEchoRuntime(false), Images: Images{PullPolicy: PullNever}}
+	server := catalog.Server{Type: "server", Image: echoImage}
+
+	_, err := transport.CreateSession(t.Context(), mcp.NewClient(&mcp.Implementation{Name: "test"}, nil), server, "echo")
+	if err == nil || !strings.Contains(err.Error(), "not present locally") {
+		t.Errorf("got %v, want the missing image reported", err)
+	}
+}
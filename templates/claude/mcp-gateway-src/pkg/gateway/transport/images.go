@@ -0,0 +1,225 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"e2b.dev/mcp-gateway/pkg/containers"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	dockerconfig "github.com/docker/go-sdk/config"
+	"go.uber.org/zap"
+)
+
+// Image pull policies
+const (
+	PullAlways       = "always"         // Pull before every container start
+	PullIfNotPresent = "if-not-present" // Pull only when the image is missing locally (default)
+	PullNever        = "never"          // Never contact a registry, fail when the image is missing
+)
+
+// dockerHubServerAddress is the address Docker Hub credentials are stored under
+const dockerHubServerAddress = "https://index.docker.io/v1/"
+
+// ValidPullPolicy reports whether policy is a known pull policy, empty selects the default
+func ValidPullPolicy(policy string) bool {
+	switch policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return true
+	default:
+		return false
+	}
+}
+
+// RegistryAuth holds the credentials of one registry
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identityToken,omitempty"`
+}
+
+// Images controls how server images are obtained
+type Images struct {
+	PullPolicy  string                  `json:"pullPolicy,omitempty"`  // "always", "if-not-present" (default) or "never"
+	Mirrors     map[string]string       `json:"mirrors,omitempty"`     // Mirror host keyed by registry host, e.g. "docker.io"
+	Credentials map[string]RegistryAuth `json:"credentials,omitempty"` // Keyed by registry or mirror host, DOCKER_CONFIG is used for other hosts
+}
+
+// Validate rejects unknown pull policies and empty mirrors
+func (i Images) Validate() error {
+	if !ValidPullPolicy(i.PullPolicy) {
+		return fmt.Errorf("unknown pull policy %q", i.PullPolicy)
+	}
+	for registryHost, mirror := range i.Mirrors {
+		if mirror == "" {
+			return fmt.Errorf("empty mirror for registry %q", registryHost)
+		}
+	}
+	return nil
+}
+
+// EnsureImage makes the image available to the runtime according to the pull policy and returns
+// the reference it is available under, which differs from ref when it was pulled from a mirror
+func EnsureImage(ctx context.Context, runtime containers.Runtime, ref string, images Images) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+
+	mirrored := ""
+	if mirror, ok := lookupRegistry(images.Mirrors, reference.Domain(named)); ok {
+		mirrored = mirrorReference(named, mirror)
+	}
+
+	policy := images.PullPolicy
+	if policy == "" {
+		policy = PullIfNotPresent
+	}
+
+	if policy != PullAlways {
+		candidates := []string{ref}
+		if tag := bundleTag(named); tag != ref {
+			candidates = append(candidates, tag)
+		}
+		if mirrored != "" {
+			candidates = append(candidates, mirrored)
+		}
+		for _, candidate := range candidates {
+			present, err := runtime.ImagePresent(ctx, candidate)
+			if err != nil {
+				return "", err
+			}
+			if present {
+				return candidate, nil
+			}
+		}
+		if policy == PullNever {
+			return "", fmt.Errorf("image %s is not present locally and the pull policy is %q", ref, PullNever)
+		}
+	}
+
+	if mirrored != "" {
+		err := pullImage(ctx, runtime, mirrored, images.Credentials)
+		if err == nil {
+			return mirrored, nil
+		}
+		zap.L().Warn("Failed to pull image from mirror, pulling from upstream",
+			zap.String("component", "PULL"),
+			zap.String("image", ref),
+			zap.String("mirror", mirrored),
+			zap.Error(err))
+	}
+
+	if err := pullImage(ctx, runtime, ref, images.Credentials); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// BundleTag returns the reference an image is kept under after it was loaded from a bundle.
+// Digest-pinned images get a tag derived from their digest, since image stores
+// without containerd drop repository digests when loading images.
+func BundleTag(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+	return bundleTag(named), nil
+}
+
+// bundleTag returns the bundle tag of a parsed reference
+func bundleTag(named reference.Named) string {
+	if digested, ok := named.(reference.Digested); ok {
+		digest := digested.Digest()
+		return reference.FamiliarName(named) + ":" + digest.Algorithm().String() + "-" + digest.Encoded()
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
+// pullImage pulls ref with the credentials of its registry
+func pullImage(ctx context.Context, runtime containers.Runtime, ref string, credentials map[string]RegistryAuth) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+
+	registryAuth, err := encodeRegistryAuth(reference.Domain(named), credentials)
+	if err != nil {
+		return err
+	}
+	return runtime.Pull(ctx, ref, registryAuth)
+}
+
+// encodeRegistryAuth returns the X-Registry-Auth value for a registry, preferring configured
+// credentials over the ones stored in DOCKER_CONFIG. Public registries get an empty value.
+func encodeRegistryAuth(registryHost string, credentials map[string]RegistryAuth) (string, error) {
+	registryHost = normalizeRegistry(registryHost)
+
+	if creds, ok := lookupRegistry(credentials, registryHost); ok {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      creds.Username,
+			Password:      creds.Password,
+			IdentityToken: creds.IdentityToken,
+			ServerAddress: serverAddress(registryHost),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode credentials for %s: %w", registryHost, err)
+		}
+		return encoded, nil
+	}
+
+	stored, err := dockerconfig.AuthConfigForHostname(serverAddress(registryHost))
+	if err != nil {
+		zap.L().Debug("No stored registry credentials",
+			zap.String("component", "PULL"),
+			zap.String("registry", registryHost),
+			zap.Error(err))
+		return "", nil
+	}
+	encoded, err := registry.EncodeAuthConfig(stored.ToRegistryAuthConfig())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode credentials for %s: %w", registryHost, err)
+	}
+	return encoded, nil
+}
+
+// mirrorReference rewrites the registry host of a reference to the mirror host
+func mirrorReference(named reference.Named, mirror string) string {
+	ref := mirror + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref += "@" + digested.Digest().String()
+	}
+	return ref
+}
+
+// serverAddress returns the key docker uses for a registry in its config and credential helpers
+func serverAddress(registryHost string) string {
+	if registryHost == "docker.io" {
+		return dockerHubServerAddress
+	}
+	return registryHost
+}
+
+// lookupRegistry returns the entry of a map keyed by registry host, treating Docker Hub aliases as equal
+func lookupRegistry[V any](entries map[string]V, registryHost string) (V, bool) {
+	registryHost = normalizeRegistry(registryHost)
+	for host, value := range entries {
+		if normalizeRegistry(host) == registryHost {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// normalizeRegistry maps the aliases of Docker Hub to docker.io
+func normalizeRegistry(host string) string {
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", dockerHubServerAddress:
+		return "docker.io"
+	}
+	return host
+}
@@ -0,0 +1,212 @@
+package transport
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Resources limits what a server container may use.
+// Zero values mean "not set" so that global and per-server settings can be layered.
+type Resources struct {
+	CPUs           float64           `json:"cpus,omitempty"`           // Number of CPUs, fractions allowed
+	Memory         string            `json:"memory,omitempty"`         // Memory limit such as "512m" or "2g"
+	PidsLimit      int64             `json:"pidsLimit,omitempty"`      // Maximum number of processes, -1 for unlimited
+	Tmpfs          []string          `json:"tmpfs,omitempty"`          // Mounts such as "/tmp:size=64m"
+	Ulimits        map[string]string `json:"ulimits,omitempty"`        // Limits such as "nofile": "1024:2048"
+	ReadOnlyRootfs *bool             `json:"readOnlyRootfs,omitempty"` // Mount the image filesystem read-only
+}
+
+// ulimitNames are the limits Docker accepts
+var ulimitNames = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true, "memlock": true,
+	"msgqueue": true, "nice": true, "nofile": true, "nproc": true, "rss": true, "rtprio": true,
+	"rttime": true, "sigpending": true, "stack": true,
+}
+
+// browserServers are the catalog servers that run a browser in their container and need more
+// than the default. Servers driving a hosted browser through an API are not listed.
+// This is a stopgap: the catalog has no metadata telling a local browser from a hosted one
+// (tags such as "browser-automation" cover both), so the names are kept here and
+// TestBrowserServersAreCatalogEntries fails when the catalog renames or drops one of them.
+var browserServers = map[string]bool{
+	"playwright":            true,
+	"playwright-mcp-server": true,
+	"puppeteer":             true,
+}
+
+// DefaultResources returns the limits of a catalog server before user settings are applied.
+// Servers running a browser get more CPU, memory and shared memory than the rest.
+func DefaultResources(serverName string) Resources {
+	if browserServers[serverName] {
+		return Resources{
+			CPUs:      2,
+			Memory:    "2g",
+			PidsLimit: 1024,
+			Tmpfs:     []string{"/dev/shm:size=512m"},
+		}
+	}
+
+	return Resources{
+		CPUs:      1,
+		Memory:    "1g",
+		PidsLimit: 256,
+	}
+}
+
+// Merge returns r with every field that is set in override replaced
+func (r Resources) Merge(override Resources) Resources {
+	if override.CPUs != 0 {
+		r.CPUs = override.CPUs
+	}
+	if override.Memory != "" {
+		r.Memory = override.Memory
+	}
+	if override.PidsLimit != 0 {
+		r.PidsLimit = override.PidsLimit
+	}
+	if override.Tmpfs != nil {
+		r.Tmpfs = override.Tmpfs
+	}
+	if override.Ulimits != nil {
+		ulimits := maps.Clone(r.Ulimits)
+		if ulimits == nil {
+			ulimits = make(map[string]string, len(override.Ulimits))
+		}
+		maps.Copy(ulimits, override.Ulimits)
+		r.Ulimits = ulimits
+	}
+	if override.ReadOnlyRootfs != nil {
+		r.ReadOnlyRootfs = override.ReadOnlyRootfs
+	}
+	return r
+}
+
+// Validate reports settings docker would reject or that can't work
+func (r Resources) Validate() error {
+	if r.CPUs < 0 {
+		return fmt.Errorf("cpus must be positive, got %g", r.CPUs)
+	}
+
+	if r.Memory != "" {
+		bytes, err := ParseMemory(r.Memory)
+		if err != nil {
+			return err
+		}
+		if bytes < 6<<20 {
+			return fmt.Errorf("memory must be at least 6m, got %q", r.Memory)
+		}
+	}
+
+	if r.PidsLimit < -1 {
+		return fmt.Errorf("pidsLimit must be positive or -1 for unlimited, got %d", r.PidsLimit)
+	}
+
+	for _, mount := range r.Tmpfs {
+		path, _, _ := strings.Cut(mount, ":")
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("tmpfs mount %q needs an absolute path", mount)
+		}
+	}
+
+	for name, value := range r.Ulimits {
+		if !ulimitNames[name] {
+			return fmt.Errorf("unknown ulimit %q", name)
+		}
+		if _, _, err := parseUlimit(value); err != nil {
+			return fmt.Errorf("ulimit %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ParseMemory converts a docker memory size such as "512m" to bytes
+func ParseMemory(size string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(size))
+	s = strings.TrimSuffix(s, "b")
+
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		case 't':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid memory size %q", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// parseUlimit parses "n" or "soft:hard", where -1 means unlimited
+func parseUlimit(value string) (int64, int64, error) {
+	softStr, hardStr, hasHard := strings.Cut(value, ":")
+	soft, err := strconv.ParseInt(softStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value %q", value)
+	}
+	if !hasHard {
+		return soft, soft, nil
+	}
+
+	hard, err := strconv.ParseInt(hardStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value %q", value)
+	}
+	if hard != -1 && (soft == -1 || soft > hard) {
+		return 0, 0, fmt.Errorf("soft limit exceeds hard limit in %q", value)
+	}
+	return soft, hard, nil
+}
+
+// apply sets the limits on a container's host config
+func (r Resources) apply(hc *container.HostConfig) error {
+	if r.CPUs > 0 {
+		hc.NanoCPUs = int64(r.CPUs * 1e9)
+	}
+	if r.Memory != "" {
+		memory, err := ParseMemory(r.Memory)
+		if err != nil {
+			return err
+		}
+		hc.Memory = memory
+	}
+	if r.PidsLimit != 0 {
+		pids := r.PidsLimit
+		hc.PidsLimit = &pids
+	}
+	if len(r.Tmpfs) > 0 {
+		hc.Tmpfs = make(map[string]string, len(r.Tmpfs))
+		for _, mount := range r.Tmpfs {
+			path, options, _ := strings.Cut(mount, ":")
+			hc.Tmpfs[path] = options
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Ulimits)) {
+		soft, hard, err := parseUlimit(r.Ulimits[name])
+		if err != nil {
+			return fmt.Errorf("ulimit %s: %w", name, err)
+		}
+		hc.Ulimits = append(hc.Ulimits, &container.Ulimit{Name: name, Soft: soft, Hard: hard})
+	}
+	if r.ReadOnlyRootfs != nil {
+		hc.ReadonlyRootfs = *r.ReadOnlyRootfs
+	}
+	return nil
+}
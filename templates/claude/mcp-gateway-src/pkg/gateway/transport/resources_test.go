@@ -0,0 +1,35 @@
+package transport
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+)
+
+func TestBrowserServersAreCatalogEntries(t *testing.T) {
+	path, err := filepath.Abs("../../../docker-catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := catalog.ReadFrom(t.Context(), []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.Servers) == 0 {
+		t.Fatalf("no servers read from %s", path)
+	}
+
+	for name := range browserServers {
+		server, ok := cat.Servers[name]
+		if !ok {
+			t.Errorf("%s is not in the catalog", name)
+			continue
+		}
+		// The image has to be the one that bundles the browser, not a client of a hosted one
+		if server.Type != "server" || !strings.Contains(server.Image, "playwright") && !strings.Contains(server.Image, "puppeteer") {
+			t.Errorf("%s runs %s, not a local browser", name, server.Image)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+)
+
+// Secret delivery modes
+const (
+	// SecretsEnv passes secrets as environment variables in the container create request
+	SecretsEnv = "env"
+	// SecretsFiles mounts each secret as a read-only file under /run/secrets and sets <NAME>_FILE to its path.
+	// Only servers that read <NAME>_FILE can use it, and the runtime must share the gateway's filesystem.
+	SecretsFiles = "files"
+)
+
+// secretsMountPath is where secret files appear inside the container
+const secretsMountPath = "/run/secrets"
+
+// ValidSecretDelivery reports whether mode names a supported delivery mode
+func ValidSecretDelivery(mode string) bool {
+	return mode == "" || mode == SecretsEnv || mode == SecretsFiles
+}
+
+// containerEnv holds a container's environment and the temporary files backing its secrets.
+// Neither ever ends up on a command line.
+type containerEnv struct {
+	env        []string
+	binds      []string
+	secretsDir string // Private to the gateway user, removed when the container exits
+	mountDir   string // Directory inside secretsDir that is mounted into the container
+}
+
+// secretsBaseDir prefers a tmpfs so secrets never reach a disk
+func secretsBaseDir() string {
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm"
+	}
+	return os.TempDir()
+}
+
+// prepareEnv builds a server's environment, writing secrets to files in the files delivery mode
+func prepareEnv(server catalog.Server, extra []catalog.Env, mode string) (*containerEnv, error) {
+	secretEnvs := make(map[string]bool, len(server.Secrets))
+	for _, s := range server.Secrets {
+		secretEnvs[s.Env] = true
+	}
+
+	env := &containerEnv{}
+	for _, e := range append(append([]catalog.Env(nil), server.Env...), extra...) {
+		if e.Name == "" || e.Value == "" {
+			continue
+		}
+
+		if mode == SecretsFiles && secretEnvs[e.Name] {
+			if env.secretsDir == "" {
+				// The private parent keeps other users of the host away from the mounted directory
+				dir, err := os.MkdirTemp(secretsBaseDir(), "mcp-secrets-*")
+				if err != nil {
+					return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+				}
+				env.secretsDir = dir
+				env.mountDir = filepath.Join(dir, "secrets")
+				if err := os.Mkdir(env.mountDir, 0o700); err != nil {
+					env.cleanup()
+					return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+				}
+				env.binds = append(env.binds, env.mountDir+":"+secretsMountPath+":ro")
+			}
+			if err := os.WriteFile(filepath.Join(env.mountDir, e.Name), []byte(e.Value), 0o600); err != nil {
+				env.cleanup()
+				return nil, fmt.Errorf("failed to write secret %s: %w", e.Name, err)
+			}
+			env.env = append(env.env, fmt.Sprintf("%s_FILE=%s/%s", e.Name, secretsMountPath, e.Name))
+			continue
+		}
+
+		env.env = append(env.env, e.Name+"="+e.Value)
+	}
+
+	if env.mountDir != "" {
+		if err := env.shareSecrets(server.User); err != nil {
+			env.cleanup()
+			return nil, fmt.Errorf("failed to share secrets with the container: %w", err)
+		}
+	}
+
+	return env, nil
+}
+
+// shareSecrets makes the secret files readable by the container user. A numeric user owns the files
+// when the gateway may change their owner, other users (named ones or the image's default user) get
+// read access like everyone else in the container.
+func (e *containerEnv) shareSecrets(user string) error {
+	entries, err := os.ReadDir(e.mountDir)
+	if err != nil {
+		return err
+	}
+	paths := []string{e.mountDir}
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(e.mountDir, entry.Name()))
+	}
+
+	dirMode, fileMode := os.FileMode(0o555), os.FileMode(0o444)
+	if uid, ok := numericUser(user); ok {
+		var chownErr error
+		for _, path := range paths {
+			chownErr = errors.Join(chownErr, os.Lchown(path, uid, -1))
+		}
+		if chownErr == nil {
+			dirMode, fileMode = 0o500, 0o400
+		}
+	}
+
+	for _, path := range paths[1:] {
+		if err := os.Chmod(path, fileMode); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(e.mountDir, dirMode)
+}
+
+// numericUser returns the uid of a container user given as "uid" or "uid:gid"
+func numericUser(user string) (int, bool) {
+	name, _, _ := strings.Cut(user, ":")
+	uid, err := strconv.Atoi(name)
+	if err != nil || uid < 0 {
+		return 0, false
+	}
+	return uid, true
+}
+
+// cleanup removes the secret files
+func (e *containerEnv) cleanup() {
+	if e.secretsDir != "" {
+		// The mounted directory was made read-only for the container
+		os.Chmod(e.mountDir, 0o700)
+		os.RemoveAll(e.secretsDir)
+		e.secretsDir = ""
+	}
+}
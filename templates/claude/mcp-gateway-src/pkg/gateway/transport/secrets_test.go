@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/docker/mcp-gateway/pkg/catalog"
+)
+
+func TestPrepareEnvSharesSecretFilesWithTheContainer(t *testing.T) {
+	server := catalog.Server{
+		User:    "node",
+		Secrets: []catalog.Secret{{Name: "brave.api_key", Env: "BRAVE_API_KEY"}},
+		Env:     []catalog.Env{{Name: "BRAVE_API_KEY", Value: "secret"}, {Name: "LOG_LEVEL", Value: "info"}},
+	}
+
+	env, err := prepareEnv(server, nil, SecretsFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.cleanup()
+
+	want := []string{"BRAVE_API_KEY_FILE=/run/secrets/BRAVE_API_KEY", "LOG_LEVEL=info"}
+	if !slices.Equal(env.env, want) {
+		t.Errorf("env: got %v, want %v", env.env, want)
+	}
+
+	// A named user gets read access, the parent directory stays private to the gateway
+	file, err := os.Stat(filepath.Join(env.mountDir, "BRAVE_API_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := file.Mode().Perm(); mode != 0o444 {
+		t.Errorf("secret file mode: got %o, want 444", mode)
+	}
+	parent, err := os.Stat(env.secretsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := parent.Mode().Perm(); mode != 0o700 {
+		t.Errorf("secrets directory mode: got %o, want 700", mode)
+	}
+
+	dir := env.secretsDir
+	env.cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("secrets directory was not removed: %v", err)
+	}
+}
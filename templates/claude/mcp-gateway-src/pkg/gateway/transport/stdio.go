@@ -0,0 +1,158 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxMessageSize bounds a single newline-delimited JSON-RPC message read from a server
+const maxMessageSize = 64 << 20
+
+// stdioConn is an MCP connection speaking newline-delimited JSON over a server's stdin and stdout
+type stdioConn struct {
+	writeMu sync.Mutex
+	stdin   io.Writer
+
+	messages chan readResult
+	done     chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+	closeFunc func() error
+
+	// exitErr explains a closed stdout, for example with the exit code of the container
+	exitErr func() error
+}
+
+// readResult is a line read from stdout
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// newStdioConn starts reading stdout. closeFunc is called once when the connection closes.
+func newStdioConn(stdin io.Writer, stdout io.Reader, closeFunc func() error, exitErr func() error) *stdioConn {
+	c := &stdioConn{
+		stdin:     stdin,
+		messages:  make(chan readResult),
+		done:      make(chan struct{}),
+		closeFunc: closeFunc,
+		exitErr:   exitErr,
+	}
+
+	go func() {
+		reader := bufio.NewReaderSize(stdout, 64<<10)
+		for {
+			line, err := readLine(reader)
+			select {
+			case c.messages <- readResult{data: line, err: err}:
+			case <-c.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// readLine reads the next non-empty line without its "\n" or "\r\n" terminator,
+// rejecting lines above maxMessageSize
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, errors.New("message from server exceeds the size limit")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) == 0 {
+			// Blank lines between messages are not messages
+			line = line[:0]
+			continue
+		}
+		return line, nil
+	}
+}
+
+// Read implements mcp.Connection
+func (c *stdioConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, io.EOF
+	case result := <-c.messages:
+		if result.err != nil {
+			if errors.Is(result.err, io.EOF) && c.exitErr != nil {
+				if err := c.exitErr(); err != nil {
+					return nil, err
+				}
+			}
+			return nil, result.err
+		}
+		return jsonrpc.DecodeMessage(result.data)
+	}
+}
+
+// Write implements mcp.Connection
+func (c *stdioConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+		return io.ErrClosedPipe
+	default:
+	}
+	_, err = c.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// Close implements mcp.Connection
+func (c *stdioConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.closeFunc != nil {
+			c.closeErr = c.closeFunc()
+		}
+	})
+	return c.closeErr
+}
+
+// SessionID implements mcp.Connection
+func (c *stdioConn) SessionID() string {
+	return ""
+}
+
+// connTransport hands out an already established connection
+type connTransport struct {
+	conn mcp.Connection
+}
+
+// Connect implements mcp.Transport
+func (t *connTransport) Connect(context.Context) (mcp.Connection, error) {
+	return t.conn, nil
+}
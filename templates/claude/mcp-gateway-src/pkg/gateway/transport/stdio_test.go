@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+)
+
+func TestStdioConnSkipsBlankLinesAndTerminators(t *testing.T) {
+	stdout := strings.NewReader("\n" +
+		`{"jsonrpc":"2.0","id":1,"result":{}}` + "\r\n" +
+		"  \r\n\n" +
+		`{"jsonrpc":"2.0","method":"notifications/progress","params":{}}` + "\n")
+	conn := newStdioConn(io.Discard, stdout, nil, nil)
+	defer conn.Close()
+
+	first, err := conn.Read(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := first.(*jsonrpc.Response); !ok {
+		t.Errorf("got %T, want the response", first)
+	}
+	second, err := conn.Read(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request, ok := second.(*jsonrpc.Request); !ok || request.Method != "notifications/progress" {
+		t.Errorf("got %#v, want the notification", second)
+	}
+	if _, err := conn.Read(t.Context()); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v at the end of stdout, want EOF", err)
+	}
+}
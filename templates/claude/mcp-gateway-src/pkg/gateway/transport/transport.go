@@ -3,6 +3,7 @@ package transport
 import (
 	"context"
 
+	"e2b.dev/mcp-gateway/pkg/containers"
 	"github.com/docker/mcp-gateway/pkg/catalog"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -19,14 +20,48 @@ type Transport interface {
 	CreateSession(ctx context.Context, client *mcp.Client, server catalog.Server, serverName string) (*mcp.ClientSession, error)
 }
 
+// Transport names used to select per-transport settings
+const (
+	DockerName = "docker"
+	RemoteName = "remote"
+	GitHubName = "github"
+)
+
+// Name returns the name of the transport used for the given server type
+func Name(serverType string) string {
+	switch serverType {
+	case "remote":
+		return RemoteName
+	case "github":
+		return GitHubName
+	default:
+		return DockerName
+	}
+}
+
+// Egress provides filtered internet access to containers whose server declares allowHosts
+type Egress interface {
+	// Endpoint returns the network to attach the server's container to and the URL of the proxy it must use
+	Endpoint(ctx context.Context, serverName string, allowHosts []string) (network string, proxyURL string, err error)
+}
+
+// Options holds the per-server settings transports apply when creating a session
+type Options struct {
+	Runtime   containers.Runtime // Runs the containers of the Docker transport
+	Resources Resources          // Container limits, used by the Docker transport
+	Egress    Egress             // Enforces allowHosts, nil leaves egress unrestricted
+	Secrets   string             // Secret delivery mode of the Docker transport
+	Images    Images             // How the Docker transport obtains images
+}
+
 // GetTransport returns the appropriate transport implementation for the given server type
-func GetTransport(serverType string) Transport {
+func GetTransport(serverType string, opts Options) Transport {
 	switch serverType {
 	case "remote":
 		return &RemoteTransport{}
 	case "github":
 		return &GitHubTransport{}
 	default:
-		return &DockerTransport{}
+		return &DockerTransport{Runtime: opts.Runtime, Resources: opts.Resources, Egress: opts.Egress, Secrets: opts.Secrets, Images: opts.Images}
 	}
 }
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// compileSchema resolves a schema captured from a backend so it can validate instances.
+// Backends often declare older drafts; their keywords are close enough to 2020-12 for argument checks,
+// so the $schema declaration is dropped instead of skipping validation altogether.
+func compileSchema(raw any) (*jsonschema.Resolved, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	schema.Schema = ""
+
+	return schema.Resolve(nil)
+}
+
+// compileToolSchema compiles a tool schema, logging and returning nil when it can't be used
+func compileToolSchema(toolName string, kind string, raw any) *jsonschema.Resolved {
+	resolved, err := compileSchema(raw)
+	if err != nil {
+		zap.L().Debug("Skipping schema validation, schema can't be compiled",
+			zap.String("component", "TOOLS"),
+			zap.String("tool", toolName),
+			zap.String("schema", kind),
+			zap.Error(err))
+		return nil
+	}
+	return resolved
+}
+
+// validateArguments checks tool arguments against the tool's input schema, returning the
+// arguments with schema defaults applied. The arguments are forwarded byte for byte, only the
+// missing properties that got a default are appended, so large integers and key order survive.
+func validateArguments(schema *jsonschema.Resolved, arguments json.RawMessage, applyDefaults bool) (json.RawMessage, error) {
+	// A call without arguments is validated as an empty object
+	args := map[string]any{}
+	if !emptyArguments(arguments) {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+
+	given := make(map[string]bool, len(args))
+	for key := range args {
+		given[key] = true
+	}
+
+	if applyDefaults {
+		if err := schema.ApplyDefaults(&args); err != nil {
+			return nil, fmt.Errorf("applying defaults: %w", err)
+		}
+	}
+
+	if err := schema.Validate(args); err != nil {
+		return nil, err
+	}
+
+	var defaulted []string
+	for key := range args {
+		if !given[key] {
+			defaulted = append(defaulted, key)
+		}
+	}
+	if len(defaulted) == 0 {
+		return arguments, nil
+	}
+	slices.Sort(defaulted)
+	return appendProperties(arguments, args, defaulted)
+}
+
+// emptyArguments reports whether a call passed no arguments
+func emptyArguments(arguments json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(arguments)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// appendProperties appends the given keys of values to the raw JSON object, leaving its other bytes untouched
+func appendProperties(arguments json.RawMessage, values map[string]any, keys []string) (json.RawMessage, error) {
+	object := []byte("{}")
+	if !emptyArguments(arguments) {
+		object = bytes.TrimSpace(arguments)
+	}
+
+	// Validation passed, so the arguments are an object ending with its closing brace
+	body := bytes.TrimSpace(object[:len(object)-1])
+	out := append([]byte(nil), body...)
+	for i, key := range keys {
+		if i > 0 || len(bytes.TrimSpace(body[1:])) > 0 {
+			out = append(out, ',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("encoding default of %s: %w", key, err)
+		}
+		out = append(out, name...)
+		out = append(out, ':')
+		out = append(out, value...)
+	}
+	return append(out, '}'), nil
+}
+
+// validateStructuredContent checks a successful result against the tool's output schema
+func validateStructuredContent(schema *jsonschema.Resolved, result *mcp.CallToolResult) error {
+	if result.StructuredContent == nil {
+		return fmt.Errorf("tool declares an outputSchema but returned no structuredContent")
+	}
+
+	// Validate the JSON form so typed values and decoded maps are treated alike
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return fmt.Errorf("structuredContent can't be encoded: %w", err)
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return fmt.Errorf("structuredContent can't be decoded: %w", err)
+	}
+
+	return schema.Validate(instance)
+}
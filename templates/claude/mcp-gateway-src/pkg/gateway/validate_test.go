@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateArgumentsKeepsRawArguments(t *testing.T) {
+	schema, err := compileSchema(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":    map[string]any{"type": "integer"},
+			"limit": map[string]any{"type": "integer", "default": 10},
+			"query": map[string]any{"type": "string"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+	}{
+		{"no default applied", `{"query": "x", "id": 9007199254740993, "limit": 5}`, `{"query": "x", "id": 9007199254740993, "limit": 5}`},
+		{"default appended", `{"query":"x","id":9007199254740993}`, `{"query":"x","id":9007199254740993,"limit":10}`},
+		{"empty object", `{ }`, `{"limit":10}`},
+		{"no arguments", ``, `{"limit":10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateArguments(schema, json.RawMessage(tt.arguments), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,8 @@ package utils
 import (
 	"context"
 
-	apiimage "github.com/docker/docker/api/types/image"
 	client2 "github.com/docker/docker/client"
 	dockerclient "github.com/docker/go-sdk/client"
-	"github.com/docker/go-sdk/image"
 )
 
 const UserAgent = "E2B/0.0.1"
@@ -18,11 +16,11 @@ func NewDockerClient(ctx context.Context) (*dockerclient.Client, error) {
 	))
 }
 
-// PullImage pulls the specified Docker image using the provided client.
-func PullImage(ctx context.Context, serverImage string, client *dockerclient.Client) error {
-	return image.Pull(ctx,
-		serverImage,
-		image.WithPullClient(client),
-		image.WithPullOptions(apiimage.PullOptions{}),
+// NewEngineClient creates a Docker Engine API client configured from the environment (DOCKER_HOST etc.)
+func NewEngineClient() (*client2.Client, error) {
+	return client2.NewClientWithOpts(
+		client2.FromEnv,
+		client2.WithAPIVersionNegotiation(),
+		client2.WithUserAgent(UserAgent),
 	)
 }